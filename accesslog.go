@@ -0,0 +1,150 @@
+package slog
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// AccessFormatNone logs http requests as structured fields only.
+	AccessFormatNone = ""
+	// AccessFormatCommon adds the Apache Common Log Format line as message.
+	AccessFormatCommon = "common"
+	// AccessFormatCombined adds the Apache Combined Log Format line as
+	// message.
+	AccessFormatCombined = "combined"
+)
+
+// clfTimeFormat is the time format of the Common Log Format.
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// HTTPHandler wraps h to log one event per request with its status, size,
+// duration, remote address, user agent and request id. The request context
+// carries a child of logger with the request id, see FromContext.
+func HTTPHandler(logger FullLogger, h http.Handler, opts ...MiddlewareOption) http.Handler {
+	o := newMiddlewareOptions(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.excluded(r.URL.Path) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		id := r.Header.Get(o.requestIDHeader)
+		if id == "" {
+			id = newRequestID()
+			w.Header().Set(o.requestIDHeader, id)
+		}
+
+		ctx := WithLogger(r.Context(), logger.Clone().WithFields(
+			"request_id", id,
+			"method", r.Method,
+			"path", r.URL.Path,
+		))
+		rw := &responseWriter{ResponseWriter: w}
+		h.ServeHTTP(rw, r.WithContext(ctx))
+
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		kvs := []interface{}{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"proto", r.Proto,
+			"status", status,
+			"bytes", rw.size,
+			"latency", time.Since(start).Seconds(),
+			"remote", r.RemoteAddr,
+			"user_agent", r.UserAgent(),
+			"request_id", id,
+		}
+		if line := accessLine(o.accessFormat, r, start, status, rw.size); line != "" {
+			kvs = append([]interface{}{line}, kvs...)
+		}
+		logger.Log(o.level(status), kvs...)
+	})
+}
+
+// accessLine renders a request in the Apache Common or Combined Log Format.
+func accessLine(format string, r *http.Request, start time.Time, status, size int) string {
+	if format != AccessFormatCommon && format != AccessFormatCombined {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	user := "-"
+	if r.URL.User != nil && r.URL.User.Username() != "" {
+		user = r.URL.User.Username()
+	} else if name, _, ok := r.BasicAuth(); ok && name != "" {
+		user = name
+	}
+	bytes := "-"
+	if size > 0 {
+		bytes = fmt.Sprint(size)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s - %s [%s] \"%s %s %s\" %d %s",
+		host, user, start.Format(clfTimeFormat), r.Method, r.RequestURI, r.Proto, status, bytes)
+	if format == AccessFormatCombined {
+		fmt.Fprintf(&b, " %q %q", r.Referer(), r.UserAgent())
+	}
+	return b.String()
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// responseWriter records the status and the size of a response.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("slog: %T does not support hijacking", w.ResponseWriter)
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
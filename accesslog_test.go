@@ -0,0 +1,108 @@
+package slog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := &Helper{log: newZerolog(buf)}
+
+	h := HTTPHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("inside")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("hello"))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/tea?cup=1", nil)
+	r.Header.Set("User-Agent", "test-agent")
+	r.Header.Set("X-Request-Id", "req-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusTeapot, w.Code)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var child, access map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &child))
+	assert.Equal(t, "req-1", child["request_id"])
+	assert.Equal(t, "inside", child["msg"])
+
+	require.NoError(t, json.Unmarshal(lines[1], &access))
+	assert.Equal(t, "warn", access["level"])
+	assert.Equal(t, "GET", access["method"])
+	assert.Equal(t, "/tea", access["path"])
+	assert.Equal(t, float64(http.StatusTeapot), access["status"])
+	assert.Equal(t, float64(5), access["bytes"])
+	assert.Equal(t, "192.0.2.1:1234", access["remote"])
+	assert.Equal(t, "test-agent", access["user_agent"])
+	assert.Equal(t, "req-1", access["request_id"])
+	assert.NotContains(t, access, "msg")
+}
+
+func TestHTTPHandler_requestID(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := &Helper{log: newZerolog(buf)}
+
+	h := HTTPHandler(logger, http.NotFoundHandler(), WithRequestIDHeader("X-Trace"))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	id := w.Header().Get("X-Trace")
+	assert.Len(t, id, 32)
+	assert.Contains(t, buf.String(), id)
+}
+
+func TestHTTPHandler_excludes(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := &Helper{log: newZerolog(buf)}
+
+	h := HTTPHandler(logger, http.NotFoundHandler(), WithExcludes("/healthz"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Empty(t, buf.String())
+}
+
+func TestHTTPHandler_accessFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: AccessFormatCommon,
+			want:   `^192\.0\.2\.1 - alice \[[^\]]+\] "POST /form HTTP/1\.1" 200 2$`,
+		},
+		{
+			format: AccessFormatCombined,
+			want:   `^192\.0\.2\.1 - alice \[[^\]]+\] "POST /form HTTP/1\.1" 200 2 "http://example\.com/" "test-agent"$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			logger := &Helper{log: newZerolog(buf)}
+
+			h := HTTPHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			}), WithAccessFormat(tt.format))
+
+			r := httptest.NewRequest(http.MethodPost, "/form", nil)
+			r.SetBasicAuth("alice", "secret")
+			r.Header.Set("Referer", "http://example.com/")
+			r.Header.Set("User-Agent", "test-agent")
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			var access map[string]interface{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &access))
+			assert.Regexp(t, regexp.MustCompile(tt.want), access["msg"])
+		})
+	}
+}
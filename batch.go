@@ -0,0 +1,299 @@
+package slog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	zlog "github.com/rs/zerolog"
+)
+
+// retryBackoff is the wait before the first retry of a batch, it doubles
+// on each retry.
+var retryBackoff = 100 * time.Millisecond
+
+var (
+	_ zlog.LevelWriter = (*batchWriter)(nil)
+	_ io.Closer        = (*batchWriter)(nil)
+)
+
+// record is an event waiting to be sent.
+type record struct {
+	Level Level           `json:"l"`
+	Time  int64           `json:"t"`
+	Event json.RawMessage `json:"e"`
+}
+
+// batchOptions configures a batchWriter.
+type batchOptions struct {
+	// batchSize is the maximum number of events in a batch.
+	batchSize int
+	// maxBuffer is the maximum number of events kept in memory.
+	maxBuffer int
+	// interval is the maximum time an event waits before being sent.
+	interval time.Duration
+	// spillDir stores the batches that cannot be sent, if set.
+	spillDir string
+}
+
+// batchWriter buffers events and hands them in batches to send, from a
+// background goroutine. Batches that cannot be delivered are spilled to
+// disk when configured, and sent again once send succeeds.
+type batchWriter struct {
+	opts batchOptions
+	send func(batch []record) error
+
+	mu       sync.Mutex
+	buf      []record
+	spilling []record // overflow of buf, written to disk by run.
+
+	spill int64 // sequence of spill files, used by run only.
+
+	flush   chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newBatchWriter(opts batchOptions, send func(batch []record) error) (*batchWriter, error) {
+	if opts.spillDir != "" {
+		if err := os.MkdirAll(opts.spillDir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	w := &batchWriter{
+		opts:    opts,
+		send:    send,
+		flush:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *batchWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zlog.NoLevel, p)
+}
+
+func (w *batchWriter) WriteLevel(l zlog.Level, p []byte) (int, error) {
+	r := record{
+		Level: levelOf(l),
+		Time:  TimestampFunc().UnixNano(),
+		Event: append(json.RawMessage(nil), bytes.TrimRight(p, "\n")...),
+	}
+
+	w.mu.Lock()
+	w.buf = append(w.buf, r)
+	full := len(w.buf) >= w.opts.batchSize
+	if over := len(w.buf) - w.opts.maxBuffer; over > 0 {
+		// keep memory bounded, the oldest events go to disk or are lost.
+		// The disk is written by run, up to maxBuffer events wait for it.
+		if w.opts.spillDir != "" && len(w.spilling) < w.opts.maxBuffer {
+			over = len(w.buf) - w.opts.maxBuffer/2
+			w.spilling = append(w.spilling, w.buf[:over]...)
+			full = true
+		} else {
+			reportError(fmt.Errorf("buffer full, dropped %d events", over))
+		}
+		w.buf = append(w.buf[:0:0], w.buf[over:]...)
+	}
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.flush <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Close sends the buffered events and stops the writer.
+func (w *batchWriter) Close() error {
+	w.once.Do(func() { close(w.done) })
+	<-w.stopped
+	return nil
+}
+
+func (w *batchWriter) run() {
+	defer close(w.stopped)
+	ticker := time.NewTicker(w.opts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			w.drain()
+			return
+		case <-ticker.C:
+		case <-w.flush:
+		}
+		w.drain()
+	}
+}
+
+// drain sends the buffered events and the spilled batches.
+func (w *batchWriter) drain() {
+	for {
+		w.spillOverflow()
+		w.mu.Lock()
+		n := len(w.buf)
+		if n > w.opts.batchSize {
+			n = w.opts.batchSize
+		}
+		batch := w.buf[:n:n]
+		w.buf = w.buf[n:]
+		w.mu.Unlock()
+
+		if len(batch) == 0 {
+			break
+		}
+		if err := w.send(batch); err != nil {
+			reportError(err)
+			var rejected rejectedError
+			if errors.As(err, &rejected) {
+				// sending it again would fail the same way.
+				continue
+			}
+			if w.opts.spillDir != "" {
+				w.spillBatch(batch)
+			}
+			return
+		}
+	}
+	w.resend()
+}
+
+// spillOverflow writes the events WriteLevel could not keep in memory to
+// the spill directory.
+func (w *batchWriter) spillOverflow() {
+	w.mu.Lock()
+	batch := w.spilling
+	w.spilling = nil
+	w.mu.Unlock()
+	if len(batch) > 0 {
+		w.spillBatch(batch)
+	}
+}
+
+// spillBatch writes a batch to the spill directory.
+func (w *batchWriter) spillBatch(batch []record) {
+	w.spill++
+	name := filepath.Join(w.opts.spillDir, fmt.Sprintf("%019d-%06d.ndjson", time.Now().UnixNano(), w.spill))
+	f, err := os.Create(name)
+	if err != nil {
+		reportError(fmt.Errorf("spill: %w", err))
+		return
+	}
+	defer f.Close()
+	bw := bufio.NewWriter(f)
+	e := json.NewEncoder(bw)
+	for _, r := range batch {
+		e.Encode(r)
+	}
+	if err := bw.Flush(); err != nil {
+		reportError(fmt.Errorf("spill: %w", err))
+	}
+}
+
+// resend sends the spilled batches oldest first, it stops at the first
+// failure.
+func (w *batchWriter) resend() {
+	if w.opts.spillDir == "" {
+		return
+	}
+	names, _ := filepath.Glob(filepath.Join(w.opts.spillDir, "*.ndjson"))
+	sort.Strings(names)
+	for _, name := range names {
+		batch, err := readSpill(name)
+		if err != nil {
+			// kept for inspection, out of the way of the next batches.
+			reportError(fmt.Errorf("spill: %s: %w, renamed to %s.invalid", name, err, filepath.Base(name)))
+			os.Rename(name, name+".invalid")
+			continue
+		}
+		if len(batch) > 0 {
+			if err := w.send(batch); err != nil {
+				reportError(err)
+				var rejected rejectedError
+				if !errors.As(err, &rejected) {
+					return
+				}
+			}
+		}
+		os.Remove(name)
+	}
+}
+
+func readSpill(name string) ([]record, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var batch []record
+	d := json.NewDecoder(f)
+	for {
+		var r record
+		if err := d.Decode(&r); err == io.EOF {
+			return batch, nil
+		} else if err != nil {
+			return batch, err
+		}
+		batch = append(batch, r)
+	}
+}
+
+// rejectedError is returned by send for the batches the receiver refused,
+// they are dropped rather than spilled since sending them again would fail
+// the same way.
+type rejectedError struct {
+	err error
+}
+
+func (e rejectedError) Error() string { return e.err.Error() }
+
+func (e rejectedError) Unwrap() error { return e.err }
+
+// retry calls f until it succeeds, returns a permanent error or
+// maxRetries is reached, waiting with exponential backoff in between.
+func retry(maxRetries int, f func() (temporary bool, err error)) error {
+	var err error
+	backoff := retryBackoff
+	for i := 0; i <= maxRetries; i++ {
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		var temporary bool
+		if temporary, err = f(); err == nil || !temporary {
+			return err
+		}
+	}
+	return err
+}
+
+func orDefault(v int32, def int) int {
+	if v > 0 {
+		return int(v)
+	}
+	return def
+}
+
+// reportError reports errors of background writers through ErrorHandler.
+func reportError(err error) {
+	if ErrorHandler != nil {
+		ErrorHandler(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "slog: %v\n", err)
+}
@@ -0,0 +1,142 @@
+package slog
+
+import (
+	"io"
+	"testing"
+
+	zlog "github.com/rs/zerolog"
+)
+
+func newBenchHelper() *Helper {
+	return &Helper{log: newZerolog(io.Discard)}
+}
+
+func BenchmarkInfo(b *testing.B) {
+	l := newBenchHelper()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("hello world")
+	}
+}
+
+func BenchmarkInfof(b *testing.B) {
+	l := newBenchHelper()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Infof("hello %s", "world")
+	}
+}
+
+func BenchmarkLog(b *testing.B) {
+	l := newBenchHelper()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Log(LevelInfo, "hello world", "user", "alice", "attempt", 3, "ok", true)
+	}
+}
+
+func BenchmarkWithFields(b *testing.B) {
+	l := newBenchHelper().Clone().WithFields("service", "api", "version", "1.0")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("hello world")
+	}
+}
+
+func BenchmarkCaller(b *testing.B) {
+	l := newBenchHelper()
+	l.WithCaller()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("hello world")
+	}
+}
+
+func BenchmarkDisabled(b *testing.B) {
+	l := newBenchHelper()
+	b.Run("Debug", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.Debug("hello world")
+		}
+	})
+	b.Run("Debugf", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.Debugf("hello %s", "world")
+		}
+	})
+	b.Run("Log", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.Log(LevelDebug, "hello world", "user", "alice", "attempt", 3)
+		}
+	})
+}
+
+func BenchmarkZerolog(b *testing.B) {
+	l := zlog.New(io.Discard).Level(zlog.InfoLevel)
+	b.Run("Info", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.Info().Msg("hello world")
+		}
+	})
+	b.Run("Infof", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.Info().Msgf("hello %s", "world")
+		}
+	})
+	b.Run("Log", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.Info().Str("user", "alice").Int("attempt", 3).Bool("ok", true).Msg("hello world")
+		}
+	})
+	b.Run("WithFields", func(b *testing.B) {
+		l := l.With().Str("service", "api").Str("version", "1.0").Logger()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.Info().Msg("hello world")
+		}
+	})
+	b.Run("Caller", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.Info().Caller().Msg("hello world")
+		}
+	})
+	b.Run("Disabled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.Debug().Msgf("hello %s", "world")
+		}
+	})
+}
+
+func BenchmarkEvent(b *testing.B) {
+	l := newBenchHelper()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.InfoEvent().Str("user", "alice").Int("attempt", 3).Bool("ok", true).Msg("hello world")
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	for _, f := range []string{FormatJSON, FormatCBOR} {
+		l := &Helper{log: newZerologRaw(format(&Config{Format: f}, io.Discard))}
+		b.Run(f, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l.Log(LevelInfo, "hello world", "user", "alice", "attempt", 3, "ok", true)
+			}
+		})
+	}
+}
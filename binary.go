@@ -0,0 +1,22 @@
+//go:build binary_log
+
+package slog
+
+import (
+	"bufio"
+	"bytes"
+)
+
+// binaryLog is true when built with the binary_log tag of zerolog, which
+// then encodes the events as CBOR instead of JSON.
+const binaryLog = true
+
+// eventJSON returns the JSON encoding of the event p.
+func eventJSON(p []byte) []byte {
+	r := bufio.NewReaderSize(bytes.NewReader(p), 16)
+	b, err := appendCBORJSON(make([]byte, 0, 2*len(p)), r)
+	if err != nil {
+		return p
+	}
+	return append(b, '\n')
+}
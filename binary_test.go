@@ -0,0 +1,41 @@
+//go:build binary_log
+
+package slog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryLog(t *testing.T) {
+	cbor, js := new(bytes.Buffer), new(bytes.Buffer)
+	for _, w := range []struct {
+		f   string
+		buf *bytes.Buffer
+	}{{FormatCBOR, cbor}, {FormatJSON, js}} {
+		z := newZerologRaw(format(&Config{Format: w.f}, w.buf)).WithTimestamp()
+		z.Log(LevelWarn, errors.New("boom"), "hello", "n", 3, "user", user{name: "alice"}, "caller", &Caller{Func: true})
+	}
+
+	// zerolog encodes CBOR natively, the cbor format writes it as is.
+	assert.Equal(t, byte(cborMap|cborIndefinite), cbor.Bytes()[0])
+	decoded := new(bytes.Buffer)
+	require.NoError(t, CBORToJSON(decoded, cbor))
+
+	for _, b := range [][]byte{decoded.Bytes(), js.Bytes()} {
+		var e map[string]interface{}
+		require.NoError(t, json.Unmarshal(b, &e), string(b))
+		assert.Equal(t, "warn", e["level"])
+		assert.Equal(t, "boom", e[ErrorFieldName])
+		assert.Equal(t, "hello", e[MessageFieldName])
+		assert.Equal(t, float64(3), e["n"])
+		assert.Equal(t, map[string]interface{}{"name": "alice", "roles": []interface{}{}}, e["user"])
+		assert.Equal(t, map[string]interface{}{"format": "", "func": true, "goroutine": false}, e["caller"])
+		assert.IsType(t, "", e[TimestampFieldName])
+	}
+}
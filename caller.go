@@ -0,0 +1,250 @@
+package slog
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+
+	zlog "github.com/rs/zerolog"
+)
+
+const (
+	// CallerFormatShort reports the file name of the caller.
+	CallerFormatShort = "short"
+	// CallerFormatModule reports the path of the caller relative to the root
+	// of the main module, or prefixed with the path of its module for other
+	// modules.
+	CallerFormatModule = "module"
+	// CallerFormatFull reports the absolute path of the caller.
+	CallerFormatFull = "full"
+)
+
+// callerSkipPrefixes are the function name prefixes of the frames skipped
+// when looking for the caller: this package, zerolog sending the events and
+// the kratos log helpers.
+var callerSkipPrefixes = []string{
+	packageName() + ".",
+	"github.com/rs/zerolog.",
+	"github.com/go-kratos/kratos/v2/log.",
+}
+
+// helpers holds the names of the functions marked by MarkHelper.
+var helpers sync.Map
+
+// MarkHelper marks the calling function as a logging helper, like
+// testing.T.Helper. The caller reported for the events it logs is the
+// caller of the helper instead.
+func MarkHelper() {
+	var pc [1]uintptr
+	if runtime.Callers(2, pc[:]) == 0 {
+		return
+	}
+	f, _ := runtime.CallersFrames(pc[:]).Next()
+	helpers.Store(f.Function, struct{}{})
+}
+
+// callerFrame returns the frame of the first function calling the logger
+// that is neither part of this package nor a helper, skipping skip more
+// frames from there. The stack is read in a small buffer first, grown only
+// when it holds nothing but helpers.
+func callerFrame(skip int) (runtime.Frame, bool) {
+	var buf [16]uintptr
+	pcs := buf[:]
+	for {
+		n := runtime.Callers(2, pcs)
+		left := skip
+		for _, pc := range pcs[:n] {
+			for _, f := range pcFrames(pc) {
+				if isHelperFrame(f) {
+					continue
+				}
+				if left == 0 {
+					return f, true
+				}
+				left--
+			}
+		}
+		if n < len(pcs) {
+			return runtime.Frame{}, false
+		}
+		pcs = make([]uintptr, 4*len(pcs))
+	}
+}
+
+// framesByPC caches the results of pcFrames by program counter.
+var framesByPC sync.Map
+
+// pcFrames returns the frames of the return address pc, the functions
+// inlined at pc first.
+func pcFrames(pc uintptr) []runtime.Frame {
+	if fs, ok := framesByPC.Load(pc); ok {
+		return fs.([]runtime.Frame)
+	}
+	var fs []runtime.Frame
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		f, more := frames.Next()
+		fs = append(fs, f)
+		if !more {
+			break
+		}
+	}
+	framesByPC.Store(pc, fs)
+	return fs
+}
+
+func isHelperFrame(f runtime.Frame) bool {
+	if _, ok := helpers.Load(f.Function); ok {
+		return true
+	}
+	for _, p := range callerSkipPrefixes {
+		if strings.HasPrefix(f.Function, p) {
+			// tests of this package are callers as any other.
+			return !strings.HasSuffix(f.File, "_test.go")
+		}
+	}
+	return false
+}
+
+// callerFields adds the caller f to e in the format of c.
+func callerFields(e *zlog.Event, f runtime.Frame, c *Caller) {
+	switch c.GetFormat() {
+	case CallerFormatFull:
+		e.Str(CallerFieldName, f.File+":"+strconv.Itoa(f.Line))
+	case CallerFormatModule:
+		e.Str(CallerFieldName, modulePath(mainModule, f.File, f.Function)+":"+strconv.Itoa(f.Line))
+	default:
+		e.Str(CallerFieldName, CallerMarshalFunc(f.File, f.Line))
+	}
+	if c.GetFunc() {
+		e.Str(CallerFuncFieldName, funcName(f.Function))
+	}
+	if c.GetGoroutine() {
+		e.Uint64(GoroutineFieldName, goroutineID())
+	}
+}
+
+// mainModule is the path of the main module, if known.
+var mainModule = func() string {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		return bi.Main.Path
+	}
+	return ""
+}()
+
+// modulePaths caches the results of modulePath by file.
+var modulePaths sync.Map
+
+// modulePath returns the path of file relative to the root of its module
+// when it is module, or prefixed with the path of its module otherwise.
+// Files built with -trimpath are named after their module already, the
+// module of other files is read from the closest go.mod. Files of no known
+// module are reported after the package of their function fn, or by name
+// when it is unknown too.
+func modulePath(module, file, fn string) string {
+	if p, ok := modulePaths.Load(file); ok {
+		return p.(string)
+	}
+
+	var p string
+	if filepath.IsAbs(file) {
+		if root, mod, ok := findModule(filepath.Dir(file)); ok {
+			rel, _ := filepath.Rel(root, file)
+			p = path.Join(mod, filepath.ToSlash(rel))
+		} else if pkg := funcPackage(fn); pkg != "" {
+			p = pkg + "/" + filepath.Base(file)
+		} else {
+			p = shortFileName(file)
+		}
+	} else {
+		// module@version/dir/file.go for the dependencies.
+		p = file
+		if i := strings.IndexByte(p, '@'); i >= 0 {
+			if j := strings.IndexByte(p[i:], '/'); j >= 0 {
+				p = p[:i] + p[i+j:]
+			}
+		}
+	}
+	if module != "" && strings.HasPrefix(p, module+"/") {
+		p = p[len(module)+1:]
+	}
+
+	modulePaths.Store(file, p)
+	return p
+}
+
+// findModule looks for the go.mod of the module holding dir, returning the
+// root directory and the path of the module.
+func findModule(dir string) (root, module string, ok bool) {
+	for {
+		if b, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			if module := modfileModule(b); module != "" {
+				return dir, module, true
+			}
+			return "", "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
+// modfileModule returns the module path declared in the go.mod content b.
+func modfileModule(b []byte) string {
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "module") {
+			continue
+		}
+		f := strings.Fields(line[len("module"):])
+		if len(f) == 0 {
+			continue
+		}
+		return strings.Trim(f[0], `"`+"`")
+	}
+	return ""
+}
+
+// funcPackage returns the package path of the function name fn, where the
+// dots of the last path element are escaped.
+func funcPackage(fn string) string {
+	i := strings.LastIndexByte(fn, '/') + 1
+	if j := strings.IndexByte(fn[i:], '.'); j > 0 {
+		return fn[:i] + strings.ReplaceAll(fn[i:i+j], "%2e", ".")
+	}
+	return ""
+}
+
+// funcName strips the directory of the package path of a function name.
+func funcName(fn string) string {
+	if i := strings.LastIndexByte(fn, '/'); i >= 0 {
+		return fn[i+1:]
+	}
+	return fn
+}
+
+// goroutineID parses the id of the current goroutine from its stack.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// packageName returns the import path of this package.
+func packageName() string {
+	name := runtime.FuncForPC(reflect.ValueOf(MarkHelper).Pointer()).Name()
+	return strings.TrimSuffix(name, ".MarkHelper")
+}
@@ -0,0 +1,146 @@
+package slog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"testing"
+
+	klog "github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func logWrapper(l FullLogger, msg string) {
+	MarkHelper()
+	l.Info(msg)
+}
+
+func unmarkedWrapper(l FullLogger, msg string) {
+	l.Info(msg)
+}
+
+func TestCaller(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := &Helper{log: newZerolog(buf)}
+	h.WithCaller()
+
+	defer ReplaceGlobals(h)()
+
+	here := func() string {
+		_, file, line, _ := runtime.Caller(1)
+		return CallerMarshalFunc(file, line+1)
+	}
+
+	var want []string
+	want = append(want, here())
+	Info("package")
+	want = append(want, here())
+	Infof("package %s", "format")
+	want = append(want, here())
+	h.Info("helper")
+	want = append(want, here())
+	h.Log(LevelInfo, "log")
+	want = append(want, here())
+	klog.NewHelper(h).Info("kratos")
+	want = append(want, here())
+	klog.NewHelper(klog.With(h, "k", "v")).Info("kratos with")
+	want = append(want, here())
+	logWrapper(h, "marked")
+	want = append(want, here())
+	h.Clone().WithFields("foo", "bar").Info("clone")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, len(want))
+	for i, line := range lines {
+		var e map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &e))
+		assert.Equal(t, want[i], e[CallerFieldName], fmt.Sprint(e["msg"]))
+	}
+}
+
+func TestAddCallerSkip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := &Helper{log: newZerolog(buf)}
+	h.WithCaller().AddCallerSkip(1)
+
+	_, file, line, _ := runtime.Caller(0)
+	unmarkedWrapper(h, "skipped")
+
+	var e map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+	assert.Equal(t, CallerMarshalFunc(file, line+1), e[CallerFieldName])
+}
+
+func TestCallerFormat(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	tests := []struct {
+		name   string
+		caller *Caller
+		want   string
+	}{
+		{name: "short", caller: &Caller{}, want: `^caller_test\.go:\d+$`},
+		{name: "module", caller: &Caller{Format: CallerFormatModule}, want: `^caller_test\.go:\d+$`},
+		{name: "full", caller: &Caller{Format: CallerFormatFull}, want: `^` + regexp.QuoteMeta(file) + `:\d+$`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			newZerolog(buf).setCaller(tt.caller).Log(LevelInfo, "hello")
+
+			var e map[string]interface{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+			assert.Regexp(t, tt.want, e[CallerFieldName])
+			assert.NotContains(t, e, CallerFuncFieldName)
+			assert.NotContains(t, e, GoroutineFieldName)
+		})
+	}
+}
+
+func TestCallerFormat_funcAndGoroutine(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(&Config{Caller: &Caller{Func: true, Goroutine: true}})
+	l.SetOutput(buf)
+	l.Info("hello")
+
+	var e map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+	assert.Regexp(t, `^caller_test\.go:\d+$`, e[CallerFieldName])
+	assert.Equal(t, "slog.TestCallerFormat_funcAndGoroutine", e[CallerFuncFieldName])
+	assert.Equal(t, float64(goroutineID()), e[GoroutineFieldName])
+}
+
+func Test_modulePath(t *testing.T) {
+	app := t.TempDir()
+	dep := filepath.Join(t.TempDir(), "yaml.v3@v3.0.1")
+	for dir, mod := range map[string]string{app: "example.com/app", dep: "gopkg.in/yaml.v3"} {
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("// comment\nmodule "+mod+"\n\ngo 1.18\n"), 0o644))
+	}
+
+	tests := []struct {
+		file string
+		fn   string
+		want string
+	}{
+		{file: filepath.Join(app, "main.go"), want: "main.go"},
+		{file: filepath.Join(app, "cmd", "a", "main.go"), want: "cmd/a/main.go"},
+		{file: filepath.Join(app, "cmd", "b", "main.go"), want: "cmd/b/main.go"},
+		{file: filepath.Join(dep, "decode.go"), want: "gopkg.in/yaml.v3/decode.go"},
+		{file: "example.com/app/cmd/a/main.go", want: "cmd/a/main.go"},
+		{file: "gopkg.in/yaml.v3@v3.0.1/decode.go", want: "gopkg.in/yaml.v3/decode.go"},
+		{file: "/build/src/app/internal/db/conn.go", fn: "example.com/app/internal/db.(*Conn).Close", want: "internal/db/conn.go"},
+		{file: "/build/src/app/internal/cache/conn.go", fn: "example.com/app/internal/cache.New.func1", want: "internal/cache/conn.go"},
+		{file: "/build/pkg/yaml/decode.go", fn: "gopkg.in/yaml%2ev3.Unmarshal", want: "gopkg.in/yaml.v3/decode.go"},
+		{file: "/build/src/app/main.go", fn: "main.main", want: "main/main.go"},
+		{file: "/no/module/here/x.go", want: "x.go"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, modulePath("example.com/app", tt.file, tt.fn), tt.file)
+	}
+}
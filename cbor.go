@@ -0,0 +1,726 @@
+package slog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// CBOR major types.
+const (
+	cborUint byte = iota << 5
+	cborNegInt
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const (
+	cborFalse      = cborSimple | 20
+	cborTrue       = cborSimple | 21
+	cborNull       = cborSimple | 22
+	cborUndefined  = cborSimple | 23
+	cborFloat16    = cborSimple | 25
+	cborFloat32    = cborSimple | 26
+	cborFloat64    = cborSimple | 27
+	cborIndefinite = 31
+	cborBreak      = cborSimple | cborIndefinite
+
+	// tags written by zerolog built with the binary_log tag.
+	cborTagEpochTime    = 1
+	cborTagEmbeddedJSON = 262
+	cborTagHexString    = 263
+)
+
+// cborWriter encodes the JSON events written to it as CBOR items on w. The
+// events already encoded as CBOR by zerolog built with the binary_log tag,
+// and the events it cannot encode, are written as is.
+type cborWriter struct {
+	w io.Writer
+}
+
+// cborBufs holds the buffers of cborWriter.
+var cborBufs = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 512)
+		return &b
+	},
+}
+
+func (c cborWriter) Write(p []byte) (int, error) {
+	if binaryLog || len(p) == 0 || p[0] != '{' {
+		return c.w.Write(p)
+	}
+	bp := cborBufs.Get().(*[]byte)
+	defer func() {
+		if cap(*bp) <= 64<<10 {
+			cborBufs.Put(bp)
+		}
+	}()
+
+	b, ok := jsonToCBOR((*bp)[:0], p)
+	if !ok {
+		return c.w.Write(p)
+	}
+	*bp = b
+	if _, err := c.w.Write(b); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// jsonToCBOR appends the CBOR encoding of the JSON value p to dst, keeping
+// the order of the object keys. It reports false if p is not valid JSON.
+func jsonToCBOR(dst, p []byte) ([]byte, bool) {
+	dst, p, ok := appendCBORValue(dst, skipJSONSpace(p))
+	return dst, ok && len(skipJSONSpace(p)) == 0
+}
+
+// appendCBORValue appends the CBOR encoding of the JSON value at the start
+// of p to dst, and returns the rest of p.
+func appendCBORValue(dst, p []byte) ([]byte, []byte, bool) {
+	if len(p) == 0 {
+		return dst, p, false
+	}
+	switch p[0] {
+	case '{', '[':
+		return appendCBORContainerOf(dst, p)
+	case '"':
+		return appendCBORText(dst, p)
+	case 't':
+		return appendCBORLiteral(dst, p, "true", cborTrue)
+	case 'f':
+		return appendCBORLiteral(dst, p, "false", cborFalse)
+	case 'n':
+		return appendCBORLiteral(dst, p, "null", cborNull)
+	}
+	return appendCBORNumber(dst, p)
+}
+
+// appendCBORContainerOf appends a JSON object or array as an indefinite
+// length map or array.
+func appendCBORContainerOf(dst, p []byte) ([]byte, []byte, bool) {
+	object := p[0] == '{'
+	end := byte(']')
+	if object {
+		dst = append(dst, cborMap|cborIndefinite)
+		end = '}'
+	} else {
+		dst = append(dst, cborArray|cborIndefinite)
+	}
+	p = skipJSONSpace(p[1:])
+	if len(p) > 0 && p[0] == end {
+		return append(dst, cborBreak), p[1:], true
+	}
+	for {
+		var ok bool
+		if object {
+			if len(p) == 0 || p[0] != '"' {
+				return dst, p, false
+			}
+			if dst, p, ok = appendCBORText(dst, p); !ok {
+				return dst, p, false
+			}
+			p = skipJSONSpace(p)
+			if len(p) == 0 || p[0] != ':' {
+				return dst, p, false
+			}
+			p = skipJSONSpace(p[1:])
+		}
+		if dst, p, ok = appendCBORValue(dst, p); !ok {
+			return dst, p, false
+		}
+		p = skipJSONSpace(p)
+		switch {
+		case len(p) == 0:
+			return dst, p, false
+		case p[0] == ',':
+			p = skipJSONSpace(p[1:])
+		case p[0] == end:
+			return append(dst, cborBreak), p[1:], true
+		default:
+			return dst, p, false
+		}
+	}
+}
+
+// appendCBORText appends the JSON string at the start of p as a text
+// string.
+func appendCBORText(dst, p []byte) ([]byte, []byte, bool) {
+	i := 1
+	for i < len(p) && p[i] != '"' && p[i] != '\\' {
+		i++
+	}
+	if i == len(p) {
+		return dst, p, false
+	}
+	if p[i] == '"' {
+		dst = appendCBORHead(dst, cborText, uint64(i-1))
+		return append(dst, p[1:i]...), p[i+1:], true
+	}
+
+	// unescape after room for the longest head, then move the text next to
+	// its actual head.
+	start := len(dst)
+	dst = append(dst, make([]byte, 9)...)
+	dst = append(dst, p[1:i]...)
+	for {
+		if i == len(p) {
+			return dst[:start], p, false
+		}
+		c := p[i]
+		switch {
+		case c == '"':
+			var head [9]byte
+			h := appendCBORHead(head[:0], cborText, uint64(len(dst)-start-9))
+			n := copy(dst[start:], h)
+			n += copy(dst[start+n:], dst[start+9:])
+			return dst[:start+n], p[i+1:], true
+		case c != '\\':
+			dst = append(dst, c)
+			i++
+			continue
+		}
+		if i+1 == len(p) {
+			return dst[:start], p, false
+		}
+		switch p[i+1] {
+		case '"', '\\', '/':
+			dst = append(dst, p[i+1])
+		case 'b':
+			dst = append(dst, '\b')
+		case 'f':
+			dst = append(dst, '\f')
+		case 'n':
+			dst = append(dst, '\n')
+		case 'r':
+			dst = append(dst, '\r')
+		case 't':
+			dst = append(dst, '\t')
+		case 'u':
+			r, n := unquoteJSONRune(p[i:])
+			if n == 0 {
+				return dst[:start], p, false
+			}
+			dst = utf8.AppendRune(dst, r)
+			i += n
+			continue
+		default:
+			return dst[:start], p, false
+		}
+		i += 2
+	}
+}
+
+// unquoteJSONRune decodes the \uXXXX escape at the start of p, and the
+// low surrogate following a high surrogate. It returns the number of bytes
+// read, 0 if the escape is invalid.
+func unquoteJSONRune(p []byte) (rune, int) {
+	r, ok := hexRune(p)
+	if !ok {
+		return 0, 0
+	}
+	if utf16.IsSurrogate(r) {
+		if r2, ok := hexRune(p[6:]); ok {
+			if d := utf16.DecodeRune(r, r2); d != utf8.RuneError {
+				return d, 12
+			}
+		}
+		return utf8.RuneError, 6
+	}
+	return r, 6
+}
+
+func hexRune(p []byte) (rune, bool) {
+	if len(p) < 6 || p[0] != '\\' || p[1] != 'u' {
+		return 0, false
+	}
+	var r rune
+	for _, c := range p[2:6] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c -= 'a' - 10
+		case 'A' <= c && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(c)
+	}
+	return r, true
+}
+
+func appendCBORLiteral(dst, p []byte, lit string, b byte) ([]byte, []byte, bool) {
+	if len(p) < len(lit) || string(p[:len(lit)]) != lit {
+		return dst, p, false
+	}
+	return append(dst, b), p[len(lit):], true
+}
+
+// appendCBORNumber appends the JSON number at the start of p as an integer
+// when it is one that fits, or as a float.
+func appendCBORNumber(dst, p []byte) ([]byte, []byte, bool) {
+	n := 0
+	integer := true
+	for n < len(p) {
+		c := p[n]
+		if '0' <= c && c <= '9' || c == '-' && n == 0 {
+			n++
+			continue
+		}
+		if c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-' {
+			integer = false
+			n++
+			continue
+		}
+		break
+	}
+	num := p[:n]
+	if integer {
+		neg := len(num) > 0 && num[0] == '-'
+		digits := num
+		if neg {
+			digits = num[1:]
+		}
+		if v, ok := parseDigits(digits); ok {
+			switch {
+			case !neg:
+				return appendCBORHead(dst, cborUint, v), p[n:], true
+			case v == 0:
+				return append(dst, cborUint), p[n:], true
+			default:
+				return appendCBORHead(dst, cborNegInt, v-1), p[n:], true
+			}
+		}
+	}
+	f, err := strconv.ParseFloat(string(num), 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return dst, p, false
+	}
+	return appendUint(append(dst, cborFloat64), math.Float64bits(f), 8), p[n:], true
+}
+
+// parseDigits parses the decimal digits of p, reporting false if p is empty
+// or overflows an uint64.
+func parseDigits(p []byte) (uint64, bool) {
+	if len(p) == 0 {
+		return 0, false
+	}
+	var v uint64
+	for _, c := range p {
+		d := uint64(c - '0')
+		if v > (math.MaxUint64-d)/10 {
+			return 0, false
+		}
+		v = v*10 + d
+	}
+	return v, true
+}
+
+func skipJSONSpace(p []byte) []byte {
+	for len(p) > 0 && (p[0] == ' ' || p[0] == '\n' || p[0] == '\r' || p[0] == '\t') {
+		p = p[1:]
+	}
+	return p
+}
+
+// appendCBORHead appends the head of an item of the major type major with
+// the argument v, in its shortest form.
+func appendCBORHead(dst []byte, major byte, v uint64) []byte {
+	switch {
+	case v < 24:
+		return append(dst, major|byte(v))
+	case v <= math.MaxUint8:
+		return append(dst, major|24, byte(v))
+	case v <= math.MaxUint16:
+		return appendUint(append(dst, major|25), v, 2)
+	case v <= math.MaxUint32:
+		return appendUint(append(dst, major|26), v, 4)
+	}
+	return appendUint(append(dst, major|27), v, 8)
+}
+
+// appendUint appends the n low order bytes of v, in big endian order.
+func appendUint(dst []byte, v uint64, n int) []byte {
+	for i := n - 1; i >= 0; i-- {
+		dst = append(dst, byte(v>>(8*i)))
+	}
+	return dst
+}
+
+// CBORToJSON converts the CBOR events read from src, as logged with the
+// "cbor" format, to JSON lines written to dst.
+func CBORToJSON(dst io.Writer, src io.Reader) error {
+	r := bufio.NewReader(src)
+	w := bufio.NewWriter(dst)
+	var b []byte
+	for {
+		if _, err := r.Peek(1); err == io.EOF {
+			return w.Flush()
+		}
+		var err error
+		b, err = appendCBORJSON(b[:0], r)
+		if err != nil {
+			w.Flush()
+			return err
+		}
+		b = append(b, '\n')
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+}
+
+var errCBORBreak = errors.New("slog: unexpected CBOR break")
+
+// appendCBORJSON reads a CBOR item from r and appends its JSON encoding to
+// dst.
+func appendCBORJSON(dst []byte, r *bufio.Reader) ([]byte, error) {
+	ib, err := r.ReadByte()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if ib == cborBreak {
+		return nil, errCBORBreak
+	}
+	major, info := ib&0xe0, ib&0x1f
+
+	if major == cborSimple {
+		switch ib {
+		case cborFalse:
+			return append(dst, "false"...), nil
+		case cborTrue:
+			return append(dst, "true"...), nil
+		case cborNull, cborUndefined:
+			return append(dst, "null"...), nil
+		case cborFloat16, cborFloat32, cborFloat64:
+			v, err := readCBORArg(r, info)
+			if err != nil {
+				return nil, err
+			}
+			return appendJSONFloat(dst, cborFloat(info, v)), nil
+		}
+		return nil, fmt.Errorf("slog: unsupported CBOR simple value %#x", ib)
+	}
+
+	if info == cborIndefinite {
+		switch major {
+		case cborBytes, cborText:
+			var s []byte
+			for {
+				chunk, err := readCBORString(r, major)
+				if err == errCBORBreak {
+					return appendCBORString(dst, major, s), nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				s = append(s, chunk...)
+			}
+		case cborArray, cborMap:
+			return appendCBORContainer(dst, r, major, -1)
+		}
+		return nil, fmt.Errorf("slog: invalid CBOR indefinite length item %#x", ib)
+	}
+
+	v, err := readCBORArg(r, info)
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUint:
+		return strconv.AppendUint(dst, v, 10), nil
+	case cborNegInt:
+		if v > math.MaxInt64 {
+			// -1-v does not fit in an int64.
+			return append(append(dst, '-'), bigNegInt(v)...), nil
+		}
+		return strconv.AppendInt(dst, -1-int64(v), 10), nil
+	case cborBytes, cborText:
+		s, err := readCBORBytes(r, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendCBORString(dst, major, s), nil
+	case cborArray, cborMap:
+		return appendCBORContainer(dst, r, major, int64(v))
+	}
+	return appendCBORTag(dst, r, v)
+}
+
+// bigNegInt formats 1+v, the magnitude of the CBOR negative integer of
+// argument v, when it overflows an uint64.
+func bigNegInt(v uint64) string {
+	if v < math.MaxUint64 {
+		return strconv.FormatUint(v+1, 10)
+	}
+	return "18446744073709551616"
+}
+
+// appendCBORContainer appends the JSON encoding of an array or a map of n
+// items, or up to a break if n < 0.
+func appendCBORContainer(dst []byte, r *bufio.Reader, major byte, n int64) ([]byte, error) {
+	begin, end := byte('['), byte(']')
+	if major == cborMap {
+		begin, end = '{', '}'
+	}
+	dst = append(dst, begin)
+	for i := int64(0); n < 0 || i < n; i++ {
+		if n < 0 {
+			b, err := r.Peek(1)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			if b[0] == cborBreak {
+				r.ReadByte()
+				break
+			}
+		}
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		var err error
+		if major == cborMap {
+			if dst, err = appendCBORKey(dst, r); err != nil {
+				return nil, err
+			}
+			dst = append(dst, ':')
+		}
+		if dst, err = appendCBORJSON(dst, r); err != nil {
+			return nil, err
+		}
+	}
+	return append(dst, end), nil
+}
+
+// appendCBORKey appends a map key as a JSON string, as JSON only has string
+// keys.
+func appendCBORKey(dst []byte, r *bufio.Reader) ([]byte, error) {
+	ib, err := r.Peek(1)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if ib[0]&0xe0 == cborText {
+		return appendCBORJSON(dst, r)
+	}
+	key, err := appendCBORJSON(nil, r)
+	if err != nil {
+		return nil, err
+	}
+	return appendJSONString(dst, key), nil
+}
+
+// appendCBORTag appends the JSON encoding of the item of tag, as written by
+// zerolog for times, embedded JSON and hexadecimal strings.
+func appendCBORTag(dst []byte, r *bufio.Reader, tag uint64) ([]byte, error) {
+	switch tag {
+	case cborTagEpochTime:
+		v, err := appendCBORJSON(nil, r)
+		if err != nil {
+			return nil, err
+		}
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("slog: invalid CBOR epoch time %s", v)
+		}
+		sec, frac := math.Modf(f)
+		t := time.Unix(int64(sec), int64(frac*1e9)).UTC()
+		return appendJSONString(dst, []byte(t.Format(time.RFC3339Nano))), nil
+	case cborTagEmbeddedJSON:
+		s, err := readCBORString(r, cborBytes)
+		if err != nil {
+			return nil, err
+		}
+		return append(dst, s...), nil
+	case cborTagHexString:
+		s, err := readCBORString(r, cborBytes)
+		if err != nil {
+			return nil, err
+		}
+		return appendJSONString(dst, []byte(fmt.Sprintf("%x", s))), nil
+	}
+	return appendCBORJSON(dst, r)
+}
+
+// readCBORString reads a definite length string of the major type major.
+func readCBORString(r *bufio.Reader, major byte) ([]byte, error) {
+	ib, err := r.ReadByte()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if ib == cborBreak {
+		return nil, errCBORBreak
+	}
+	if ib&0xe0 != major || ib&0x1f == cborIndefinite {
+		return nil, fmt.Errorf("slog: unexpected CBOR item %#x in string", ib)
+	}
+	n, err := readCBORArg(r, ib&0x1f)
+	if err != nil {
+		return nil, err
+	}
+	return readCBORBytes(r, n)
+}
+
+// readCBORBytes reads the n bytes of a string, growing the buffer as they
+// are read rather than trusting n.
+func readCBORBytes(r *bufio.Reader, n uint64) ([]byte, error) {
+	if n > math.MaxInt64 {
+		return nil, fmt.Errorf("slog: CBOR string of %d bytes", n)
+	}
+	if n <= uint64(r.Size()) {
+		// valid until the next read of r, enough for the callers.
+		b, err := r.Peek(int(n))
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		r.Discard(int(n))
+		return b, nil
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// readCBORArg reads the argument of an item of additional information info.
+func readCBORArg(r *bufio.Reader, info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+	if info > 27 {
+		return 0, fmt.Errorf("slog: invalid CBOR additional information %d", info)
+	}
+	var b [8]byte
+	n := 1 << (info - 24)
+	if _, err := io.ReadFull(r, b[8-n:]); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+func cborFloat(info byte, v uint64) float64 {
+	switch info {
+	case 25:
+		return float16(uint16(v))
+	case 26:
+		return float64(math.Float32frombits(uint32(v)))
+	}
+	return math.Float64frombits(v)
+}
+
+// float16 decodes an IEEE 754 half precision float.
+func float16(h uint16) float64 {
+	exp, mant := int(h>>10&0x1f), float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		f = math.Inf(1)
+		if mant != 0 {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+// appendJSONFloat appends f as JSON, which has no NaN nor infinities: they
+// are encoded as strings, as zerolog does.
+func appendJSONFloat(dst []byte, f float64) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(dst, `"NaN"`...)
+	case math.IsInf(f, 1):
+		return append(dst, `"+Inf"`...)
+	case math.IsInf(f, -1):
+		return append(dst, `"-Inf"`...)
+	}
+	// formatted as encoding/json does.
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, 64)
+	if n := len(dst); format == 'e' && n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+		// e-07 becomes e-7.
+		dst[n-2] = dst[n-1]
+		dst = dst[:n-1]
+	}
+	return dst
+}
+
+// appendCBORString appends a text string as a JSON string, and a byte
+// string as a base64 JSON string as encoding/json does.
+func appendCBORString(dst []byte, major byte, s []byte) []byte {
+	if major == cborBytes {
+		return appendJSONString(dst, []byte(base64.StdEncoding.EncodeToString(s)))
+	}
+	return appendJSONString(dst, s)
+}
+
+// appendJSONString appends s as a JSON string, replacing invalid UTF-8 with
+// U+FFFD as encoding/json does.
+func appendJSONString(dst, s []byte) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				dst = append(dst, '\\', c)
+			case c == '\n':
+				dst = append(dst, '\\', 'n')
+			case c == '\r':
+				dst = append(dst, '\\', 'r')
+			case c == '\t':
+				dst = append(dst, '\\', 't')
+			case c < 0x20:
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				dst = append(dst, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, `\ufffd`...)
+		case r == '\u2028' || r == '\u2029':
+			dst = append(dst, '\\', 'u', '2', '0', '2', hex[r&0xf])
+		default:
+			dst = append(dst, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(dst, '"')
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
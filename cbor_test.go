@@ -0,0 +1,136 @@
+package slog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCBOR_roundTrip(t *testing.T) {
+	if binaryLog {
+		t.Skip("zerolog encodes the events as CBOR")
+	}
+	log := func(z *zerolog) {
+		z.Log(LevelInfo, "hello", "n", 42, "neg", -7, "big", uint64(math.MaxUint64), "min", int64(math.MinInt64))
+		z.Log(LevelWarn, "f", 0.5, "tiny", 1e-300, "ok", true, "nil", nil, "s", "é\"\n")
+		z.Log(LevelError, errors.New("boom"), "list", []int{1, 2}, "nested", map[string]interface{}{"a": []string{}})
+		z.Log(LevelDebug, "d", 1500*time.Millisecond, "user", user{name: "alice", roles: roles{"admin"}})
+		z.Log(LevelInfo, "escapes", "tab\t \u2028 \x01 \\ / \U0001F600", "exp", 1e21)
+	}
+
+	want := new(bytes.Buffer)
+	log(newZerolog(want))
+
+	enc := new(bytes.Buffer)
+	log(newZerolog(cborWriter{w: enc}))
+	assert.Less(t, enc.Len(), want.Len())
+
+	got := new(bytes.Buffer)
+	require.NoError(t, CBORToJSON(got, enc))
+
+	wantLines := strings.Split(strings.TrimSpace(want.String()), "\n")
+	gotLines := strings.Split(strings.TrimSpace(got.String()), "\n")
+	require.Len(t, gotLines, len(wantLines))
+	for i := range wantLines {
+		assert.JSONEq(t, wantLines[i], gotLines[i])
+	}
+	// the order of the fields is kept.
+	assert.Equal(t, wantLines[0], gotLines[0])
+}
+
+func TestCBORToJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{
+			name: "definite map",
+			in:   []byte{0xa2, 0x61, 'a', 0x01, 0x61, 'b', 0x20},
+			want: `{"a":1,"b":-1}`,
+		},
+		{
+			name: "floats",
+			in:   []byte{0x83, 0xf9, 0x3c, 0x00, 0xfa, 0x3f, 0xc0, 0x00, 0x00, 0xf9, 0x7c, 0x00},
+			want: `[1,1.5,"+Inf"]`,
+		},
+		{
+			name: "epoch time",
+			in:   []byte{0xbf, 0x62, 't', 's', 0xc1, 0x1a, 0x5f, 0x5e, 0x10, 0x00, 0xff},
+			want: `{"ts":"2020-09-13T12:26:40Z"}`,
+		},
+		{
+			name: "embedded json",
+			in:   append([]byte{0xd9, 0x01, 0x06, 0x47}, `{"a":1}`...),
+			want: `{"a":1}`,
+		},
+		{
+			name: "hex string",
+			in:   []byte{0xd9, 0x01, 0x07, 0x42, 0xca, 0xfe},
+			want: `"cafe"`,
+		},
+		{
+			name: "byte and chunked strings",
+			in:   []byte{0x82, 0x42, 0x01, 0x02, 0x7f, 0x61, 'a', 0x61, 'b', 0xff},
+			want: `["AQI=","ab"]`,
+		},
+		{
+			name: "integer key",
+			in:   []byte{0xa1, 0x01, 0xf6},
+			want: `{"1":null}`,
+		},
+		{
+			name: "smallest negative",
+			in:   []byte{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			want: `-18446744073709551616`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			require.NoError(t, CBORToJSON(out, bytes.NewReader(tt.in)))
+			assert.Equal(t, tt.want+"\n", out.String())
+		})
+	}
+}
+
+func TestCBORToJSON_invalid(t *testing.T) {
+	for _, in := range [][]byte{
+		{0xbf, 0x61, 'a'},
+		{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		{0x9f, 0x01},
+		{0xff},
+		{0xfc},
+	} {
+		err := CBORToJSON(io.Discard, bytes.NewReader(in))
+		assert.Error(t, err, "%x", in)
+	}
+}
+
+func TestCBORWriter_invalid(t *testing.T) {
+	for _, in := range []string{`{"a":}`, `{"a":"\x"}`, `{"a":1} x`, `{"a":"\ud800"`} {
+		buf := new(bytes.Buffer)
+		n, err := cborWriter{w: buf}.Write([]byte(in))
+		require.NoError(t, err)
+		assert.Equal(t, len(in), n)
+		assert.Equal(t, in, buf.String(), "written as is")
+	}
+}
+
+func TestFormatCBOR(t *testing.T) {
+	buf := new(bytes.Buffer)
+	newZerologRaw(format(&Config{Format: "CBOR"}, buf)).Log(LevelInfo, "hello")
+	assert.Equal(t, byte(cborMap|cborIndefinite), buf.Bytes()[0])
+
+	out := new(bytes.Buffer)
+	require.NoError(t, CBORToJSON(out, buf))
+	assert.JSONEq(t, `{"level":"info","msg":"hello"}`, out.String())
+}
@@ -0,0 +1,7 @@
+//go:build !slog_nodebug
+
+package slog
+
+// debugEnabled is false when built with the slog_nodebug tag, the debug
+// events are then dropped at compile time.
+const debugEnabled = true
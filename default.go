@@ -4,148 +4,240 @@ import (
 	"fmt"
 	"io"
 	"os"
-
-	"gopkg.in/natefinch/lumberjack.v2"
+	"sync"
+	"sync/atomic"
 )
 
-const defaultLoggerCallerSkipFrameCount = 3
+// global holds the global logger as a globalLogger, atomic.Value needs a
+// consistent concrete type.
+var global atomic.Value
 
-var logger FullLogger
+type globalLogger struct {
+	FullLogger
+}
 
 func init() {
 	Init(nil)
 }
 
 func Init(c *Config) FullLogger {
-	logger = New(c)
+	logger := New(c)
+	global.Store(globalLogger{logger})
 	return logger
 }
 
+// ReplaceGlobals replaces the global logger with logger, safely for the
+// concurrent uses of the package functions, and returns a function
+// restoring the previous one.
+func ReplaceGlobals(logger FullLogger) func() {
+	prev := global.Swap(globalLogger{logger})
+	return func() {
+		global.Store(prev)
+	}
+}
+
 func New(c *Config) FullLogger {
-	var w io.Writer = os.Stdout
 	if c == nil {
 		c = &Config{
 			Level: "info",
 		}
 	}
 
-	if c.Path != "" {
-		logrotate := &lumberjack.Logger{
-			Filename: c.Path,
-			MaxSize:  int(c.MaxSize),
-			MaxAge:   int(c.MaxAge),
-		}
-		w = MultiLevelWriter(logrotate, os.Stdout)
+	out, err := newOutput(c, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "slog: %v, logging to stdout only\n", err)
+		out, _ = newOutput(&Config{Format: c.Format}, nil)
 	}
-
-	l := newZerolog(w)
+	l := newZerologRaw(out.w)
 	lv := ParseLevel(c.Level)
 	l.SetLevel(lv)
-	return &Helper{log: l}
+	if c.Caller != nil {
+		l.setCaller(c.Caller)
+	}
+	return &Helper{log: l, out: out, caller: c.Caller != nil}
 }
 
 func Clone() FullLogger {
-	return logger.Clone()
+	return DefaultLogger().Clone()
 }
 
 // SetLevel sets the current global log level.
 func SetLevel(lv Level) Control {
-	return logger.SetLevel(lv)
+	return DefaultLogger().SetLevel(lv)
+}
+
+// Enabled reports whether the global logger logs events of level lv.
+func Enabled(lv Level) bool {
+	return DefaultLogger().Enabled(lv)
 }
 
 // SetOutput sets the global logger output.
 func SetOutput(w io.Writer) Control {
-	return logger.SetOutput(w)
+	return DefaultLogger().SetOutput(w)
 }
 
 func WithTimestamp() FullLogger {
-	return logger.WithTimestamp()
+	return DefaultLogger().WithTimestamp()
 }
 
 func WithCaller() FullLogger {
-	return logger.WithCaller()
+	return DefaultLogger().WithCaller()
 }
 
 func WithCallerWithSkipFrameCount(skipFrameCount int) FullLogger {
-	return logger.WithCallerWithSkipFrameCount(skipFrameCount)
+	return DefaultLogger().WithCallerWithSkipFrameCount(skipFrameCount)
+}
+
+// AddCallerSkip skips skip more frames above the first caller that is not a
+// helper when reporting the caller, see MarkHelper.
+func AddCallerSkip(skip int) FullLogger {
+	return DefaultLogger().AddCallerSkip(skip)
 }
 
 func WithStack() FullLogger {
-	return logger.WithStack()
+	return DefaultLogger().WithStack()
 }
 
 func WithFields(fields ...interface{}) FullLogger {
-	return logger.WithFields(fields...)
+	return DefaultLogger().WithFields(fields...)
 }
 
-// GetLogger returns the current global
+// DefaultLogger returns the current global logger.
 func DefaultLogger() FullLogger {
-	return logger
+	return global.Load().(globalLogger).FullLogger
 }
 
 func Log(lv Level, v ...interface{}) {
-	logger.Log(lv, v...)
+	DefaultLogger().Log(lv, v...)
 }
 
 func Debug(v ...interface{}) {
-	logger.Debug(v...)
+	if !debugEnabled {
+		return
+	}
+	DefaultLogger().Debug(v...)
 }
 
 func Info(v ...interface{}) {
-	logger.Info(v...)
+	DefaultLogger().Info(v...)
 }
 
 // Printf is alias of Infof
 func Print(v ...interface{}) {
-	logger.Info(v...)
+	DefaultLogger().Info(v...)
 }
 
 func Warn(v ...interface{}) {
-	logger.Warn(v...)
+	DefaultLogger().Warn(v...)
 }
 
 func Error(v ...interface{}) {
-	logger.Error(v...)
+	DefaultLogger().Error(v...)
 }
 
 func Fatal(v ...interface{}) {
-	logger.Fatal(v...)
+	DefaultLogger().Fatal(v...)
 }
 
 // Debugf calls the default logger's Debugf method.
 func Debugf(format string, v ...interface{}) {
-	logger.Debugf(format, v...)
+	if !debugEnabled {
+		return
+	}
+	DefaultLogger().Debugf(format, v...)
 }
 
 // Infof calls the default logger's Infof method.
 func Infof(format string, v ...interface{}) {
-	logger.Infof(format, v...)
+	DefaultLogger().Infof(format, v...)
 }
 
 // Printf is alias of Infof
 func Printf(format string, v ...interface{}) {
-	logger.Infof(format, v...)
+	DefaultLogger().Infof(format, v...)
 }
 
 // Warnf calls the default logger's Warnf method.
 func Warnf(format string, v ...interface{}) {
-	logger.Warnf(format, v...)
+	DefaultLogger().Warnf(format, v...)
 }
 
 // Errorf calls the default logger's Errorf method.
 func Errorf(format string, v ...interface{}) {
-	logger.Errorf(format, v...)
+	DefaultLogger().Errorf(format, v...)
 }
 
 // Fatalf calls the default logger's Fatalf method and then os.Exit(1).
 func Fatalf(format string, v ...interface{}) {
-	logger.Fatalf(format, v...)
+	DefaultLogger().Fatalf(format, v...)
+}
+
+// Debugt calls the default logger's Debugt method.
+func Debugt(tmpl string, v ...interface{}) {
+	if !debugEnabled {
+		return
+	}
+	DefaultLogger().Debugt(tmpl, v...)
+}
+
+// Infot calls the default logger's Infot method.
+func Infot(tmpl string, v ...interface{}) {
+	DefaultLogger().Infot(tmpl, v...)
+}
+
+// Warnt calls the default logger's Warnt method.
+func Warnt(tmpl string, v ...interface{}) {
+	DefaultLogger().Warnt(tmpl, v...)
+}
+
+// Errort calls the default logger's Errort method.
+func Errort(tmpl string, v ...interface{}) {
+	DefaultLogger().Errort(tmpl, v...)
+}
+
+// Fatalt calls the default logger's Fatalt method and then os.Exit(1).
+func Fatalt(tmpl string, v ...interface{}) {
+	DefaultLogger().Fatalt(tmpl, v...)
+}
+
+// DebugEvent calls the default logger's DebugEvent method.
+func DebugEvent() Event {
+	if !debugEnabled {
+		return Event{}
+	}
+	return DefaultLogger().DebugEvent()
+}
+
+// InfoEvent calls the default logger's InfoEvent method.
+func InfoEvent() Event {
+	return DefaultLogger().InfoEvent()
+}
+
+// WarnEvent calls the default logger's WarnEvent method.
+func WarnEvent() Event {
+	return DefaultLogger().WarnEvent()
+}
+
+// ErrorEvent calls the default logger's ErrorEvent method.
+func ErrorEvent() Event {
+	return DefaultLogger().ErrorEvent()
+}
+
+// FatalEvent calls the default logger's FatalEvent method, the program exits
+// once the event is written.
+func FatalEvent() Event {
+	return DefaultLogger().FatalEvent()
 }
 
 var _ FullLogger = (*Helper)(nil)
 
 type Helper struct {
 	log *zerolog
+
+	mu  sync.Mutex
+	out *output
+	// caller reports whether the caller was enabled by the configuration.
+	caller bool
 }
 
 func (ll *Helper) Clone() FullLogger {
@@ -154,6 +246,31 @@ func (ll *Helper) Clone() FullLogger {
 	return l2
 }
 
+// apply reconfigures the level and outputs of the logger from c, keeping
+// its context fields. Outputs that are no longer used are closed after
+// releaseDelay, the events logged concurrently may still use them.
+func (ll *Helper) apply(c *Config) error {
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+	out, err := newOutput(c, ll.out)
+	if err != nil {
+		return err
+	}
+	// published at once, events are never written with the new outputs
+	// and the previous level.
+	ll.log.update(func(s *zerologState) {
+		s.setOutput(out.w)
+		s.level = ParseLevel(c.Level)
+		if c.Caller != nil || ll.caller {
+			s.setCaller(c.Caller)
+		}
+	})
+	ll.caller = c.Caller != nil
+	ll.out.releaseLater(out)
+	ll.out = out
+	return nil
+}
+
 func (ll *Helper) SetOutput(w io.Writer) Control {
 	ll.log.SetOutput(w)
 	return ll
@@ -164,18 +281,27 @@ func (ll *Helper) SetLevel(lv Level) Control {
 	return ll
 }
 
+func (ll *Helper) Enabled(lv Level) bool {
+	return ll.log.Enabled(lv)
+}
+
 func (ll *Helper) WithTimestamp() FullLogger {
 	ll.log.WithTimestamp()
 	return ll
 }
 
 func (ll *Helper) WithCaller() FullLogger {
-	ll.log.WithCallerWithSkipFrameCount(defaultLoggerCallerSkipFrameCount)
+	ll.log.WithCaller()
 	return ll
 }
 
 func (ll *Helper) WithCallerWithSkipFrameCount(skipFrameCount int) FullLogger {
-	ll.log.WithCallerWithSkipFrameCount(defaultLoggerCallerSkipFrameCount + skipFrameCount)
+	ll.log.WithCallerWithSkipFrameCount(skipFrameCount)
+	return ll
+}
+
+func (ll *Helper) AddCallerSkip(skip int) FullLogger {
+	ll.log.AddCallerSkip(skip)
 	return ll
 }
 
@@ -195,6 +321,9 @@ func (ll *Helper) Log(lv Level, v ...interface{}) error {
 }
 
 func (ll *Helper) Debug(v ...interface{}) {
+	if !debugEnabled {
+		return
+	}
 	ll.log.Log(LevelDebug, v...)
 }
 
@@ -219,25 +348,74 @@ func (ll *Helper) Fatal(v ...interface{}) {
 }
 
 func (ll *Helper) Debugf(format string, v ...interface{}) {
-	ll.log.Log(LevelDebug, fmt.Sprintf(format, v...))
+	if !debugEnabled {
+		return
+	}
+	ll.log.logf(LevelDebug, format, v)
 }
 
 func (ll *Helper) Infof(format string, v ...interface{}) {
-	ll.log.Log(LevelInfo, fmt.Sprintf(format, v...))
+	ll.log.logf(LevelInfo, format, v)
 }
 
 func (ll *Helper) Printf(format string, v ...interface{}) {
-	ll.log.Log(LevelInfo, fmt.Sprintf(format, v...))
+	ll.log.logf(LevelInfo, format, v)
 }
 
 func (ll *Helper) Warnf(format string, v ...interface{}) {
-	ll.log.Log(LevelWarn, fmt.Sprintf(format, v...))
+	ll.log.logf(LevelWarn, format, v)
 }
 
 func (ll *Helper) Errorf(format string, v ...interface{}) {
-	ll.log.Log(LevelError, fmt.Sprintf(format, v...))
+	ll.log.logf(LevelError, format, v)
 }
 
 func (ll *Helper) Fatalf(format string, v ...interface{}) {
-	ll.log.Log(LevelFatal, fmt.Sprintf(format, v...))
+	ll.log.logf(LevelFatal, format, v)
+}
+
+func (ll *Helper) Debugt(tmpl string, v ...interface{}) {
+	if !debugEnabled {
+		return
+	}
+	ll.log.logt(LevelDebug, tmpl, v)
+}
+
+func (ll *Helper) Infot(tmpl string, v ...interface{}) {
+	ll.log.logt(LevelInfo, tmpl, v)
+}
+
+func (ll *Helper) Warnt(tmpl string, v ...interface{}) {
+	ll.log.logt(LevelWarn, tmpl, v)
+}
+
+func (ll *Helper) Errort(tmpl string, v ...interface{}) {
+	ll.log.logt(LevelError, tmpl, v)
+}
+
+func (ll *Helper) Fatalt(tmpl string, v ...interface{}) {
+	ll.log.logt(LevelFatal, tmpl, v)
+}
+
+func (ll *Helper) DebugEvent() Event {
+	if !debugEnabled {
+		return Event{}
+	}
+	return ll.log.newEvent(LevelDebug)
+}
+
+func (ll *Helper) InfoEvent() Event {
+	return ll.log.newEvent(LevelInfo)
+}
+
+func (ll *Helper) WarnEvent() Event {
+	return ll.log.newEvent(LevelWarn)
+}
+
+func (ll *Helper) ErrorEvent() Event {
+	return ll.log.newEvent(LevelError)
+}
+
+func (ll *Helper) FatalEvent() Event {
+	return ll.log.newEvent(LevelFatal)
 }
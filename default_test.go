@@ -2,10 +2,14 @@ package slog
 
 import (
 	"bytes"
+	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // test package level functions without format
@@ -74,8 +78,115 @@ func TestOutput(t *testing.T) {
 	}
 
 	for _, tt := range tests {
+		if tt.testLevel == LevelDebug && !debugEnabled {
+			continue
+		}
 		SetLevel(tt.loggerLevel)
 		normalOutput(t, tt.testLevel, tt.want, tt.args...)
 		formatOutput(t, tt.testLevel, tt.want, tt.format, tt.args...)
 	}
 }
+
+func TestReplaceGlobals(t *testing.T) {
+	prev := DefaultLogger()
+	buf := new(syncBuffer)
+	l := &Helper{log: newZerolog(buf)}
+
+	undo := ReplaceGlobals(l)
+	assert.Same(t, l, DefaultLogger())
+	Info("replaced")
+	undo()
+	assert.Same(t, prev, DefaultLogger())
+	assert.Len(t, buf.events(t), 1)
+}
+
+func TestReplaceGlobals_concurrent(t *testing.T) {
+	buf := new(syncBuffer)
+	defer ReplaceGlobals(&Helper{log: newZerolog(buf)})()
+
+	other := &Helper{log: newZerolog(buf)}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			Info("global", "i", i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			ReplaceGlobals(other)()
+		}
+	}()
+	wg.Wait()
+	assert.Len(t, buf.events(t), 100)
+}
+
+// countingStringer counts how many times it is formatted.
+type countingStringer struct{ n *int }
+
+func (s countingStringer) String() string {
+	*s.n++
+	return "formatted"
+}
+
+func TestFormatDisabled(t *testing.T) {
+	buf := new(syncBuffer)
+	l := &Helper{log: newZerolog(buf)}
+	l.SetLevel(LevelWarn)
+	defer ReplaceGlobals(l)()
+
+	n := 0
+	arg := countingStringer{&n}
+	l.Debugf("%v", arg)
+	l.Infof("%v", arg)
+	l.Printf("%v", arg)
+	Debugf("%v", arg)
+	Infof("%v", arg)
+	Printf("%v", arg)
+	assert.Equal(t, 0, n)
+
+	Warnf("%v", arg)
+	l.Errorf("%v", arg)
+	assert.Equal(t, 2, n)
+	assert.Len(t, buf.events(t), 2)
+}
+
+func TestHelper_applyCaller(t *testing.T) {
+	logged := func(h *Helper) string {
+		buf := new(bytes.Buffer)
+		h.SetOutput(buf)
+		h.Info("hello")
+		return buf.String()
+	}
+
+	h := New(&Config{Level: "info", Caller: &Caller{}}).(*Helper)
+	assert.Contains(t, logged(h), `"caller":`)
+	require.NoError(t, h.apply(&Config{Level: "info"}))
+	assert.NotContains(t, logged(h), `"caller":`, "removed from the config")
+
+	h = New(&Config{Level: "info"}).(*Helper)
+	h.WithCaller()
+	require.NoError(t, h.apply(&Config{Level: "info"}))
+	assert.Contains(t, logged(h), `"caller":`, "enabled by WithCaller")
+}
+
+func TestHelper_applyRelease(t *testing.T) {
+	defer func(d time.Duration) { releaseDelay = d }(releaseDelay)
+	releaseDelay = 50 * time.Millisecond
+
+	c := new(collector)
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	h := New(&Config{Level: "info", Http: &Http{Url: srv.URL, FlushInterval: 60000}}).(*Helper)
+	// an event being logged when the configuration changes.
+	old := h.out.sinks["http"].w
+	require.NoError(t, h.apply(&Config{Level: "info"}))
+	_, err := old.Write([]byte(`{"msg":"late"}`))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return len(c.received()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, `[{"msg":"late"}]`, c.received()[0])
+}
@@ -0,0 +1,136 @@
+package slog
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/pkg/errors"
+	zlog "github.com/rs/zerolog"
+)
+
+// stackTracer is implemented by the errors of pkg/errors and
+// cockroachdb/errors carrying a stack.
+type stackTracer interface {
+	StackTrace() errors.StackTrace
+}
+
+// callersTracer is implemented by errors carrying the program counters of
+// their stack, such as the ones of go-errors/errors.
+type callersTracer interface {
+	Callers() []uintptr
+}
+
+// safeDetailer is implemented by the errors of cockroachdb/errors carrying
+// details safe for reporting.
+type safeDetailer interface {
+	SafeDetails() []string
+}
+
+// MarshalStack extracts the stack of the innermost error of the chain of err
+// carrying one, the closest to where the failure happened. Stacks of
+// pkg/errors, cockroachdb/errors and errors with a Callers() []uintptr method
+// are supported.
+func MarshalStack(err error) interface{} {
+	var pcs []uintptr
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch e := err.(type) {
+		case stackTracer:
+			st := e.StackTrace()
+			pcs = make([]uintptr, len(st))
+			for i, f := range st {
+				pcs[i] = uintptr(f)
+			}
+		case callersTracer:
+			pcs = e.Callers()
+		}
+	}
+	if len(pcs) == 0 {
+		return nil
+	}
+
+	var stack []map[string]string
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		stack = append(stack, stackFrame(f))
+		if !more {
+			return stack
+		}
+	}
+}
+
+// MarshalErrorChain renders the errors wrapped by err, err first, with their
+// type and message. Errors joining several errors render each branch under
+// "errors", kratos errors add their code, reason and metadata. It returns nil
+// if err wraps no error.
+func MarshalErrorChain(err error) interface{} {
+	if errors.Unwrap(err) == nil {
+		if _, ok := err.(interface{ Unwrap() []error }); !ok {
+			return nil
+		}
+	}
+	return errorChain(err)
+}
+
+func errorChain(err error) []map[string]interface{} {
+	var chain []map[string]interface{}
+	for err != nil {
+		c := map[string]interface{}{
+			"type": fmt.Sprintf("%T", err),
+			"msg":  err.Error(),
+		}
+		if se, ok := err.(*kerrors.Error); ok {
+			c["code"] = se.Code
+			c["reason"] = se.Reason
+			if len(se.Metadata) > 0 {
+				c["metadata"] = se.Metadata
+			}
+		}
+		if d, ok := err.(safeDetailer); ok {
+			if details := d.SafeDetails(); len(details) > 0 {
+				c["details"] = details
+			}
+		}
+		chain = append(chain, c)
+
+		if j, ok := err.(interface{ Unwrap() []error }); ok {
+			var branches [][]map[string]interface{}
+			for _, e := range j.Unwrap() {
+				if e != nil {
+					branches = append(branches, errorChain(e))
+				}
+			}
+			c["errors"] = branches
+			break
+		}
+		err = errors.Unwrap(err)
+	}
+	return chain
+}
+
+// errorFields adds the code, reason and metadata of the first kratos error of
+// the chain of err to e.
+func errorFields(e *zlog.Event, err error) {
+	var se *kerrors.Error
+	if !errors.As(err, &se) {
+		return
+	}
+	e.Int32(ErrorCodeFieldName, se.Code)
+	if se.Reason != "" {
+		e.Str(ErrorReasonFieldName, se.Reason)
+	}
+	if len(se.Metadata) > 0 {
+		e.Interface(ErrorMetadataFieldName, se.Metadata)
+	}
+}
+
+// stackFrame renders a frame in the format of ErrorStackMarshaler.
+func stackFrame(f runtime.Frame) map[string]string {
+	return map[string]string{
+		"func":   shortFuncName(f.Function),
+		"line":   strconv.Itoa(f.Line),
+		"source": shortFileName(f.File),
+	}
+}
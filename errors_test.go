@@ -0,0 +1,156 @@
+package slog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"testing"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type joinError []error
+
+func (j joinError) Error() string { return "joined" }
+
+func (j joinError) Unwrap() []error { return j }
+
+type callersError struct{ pcs []uintptr }
+
+func (e callersError) Error() string { return "callers" }
+
+func (e callersError) Callers() []uintptr { return e.pcs }
+
+func newCallersError() error {
+	pcs := make([]uintptr, 8)
+	return callersError{pcs: pcs[:runtime.Callers(1, pcs)]}
+}
+
+func newStackError() error {
+	return errors.New("origin")
+}
+
+func TestMarshalStack(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		top  string
+	}{
+		{name: "pkg/errors", err: newStackError(), top: "newStackError"},
+		{name: "wrapped", err: fmt.Errorf("outer: %w", errors.WithStack(newStackError())), top: "newStackError"},
+		{name: "callers", err: fmt.Errorf("outer: %w", newCallersError()), top: "newCallersError"},
+		{name: "none", err: fmt.Errorf("plain")},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			st := MarshalStack(tt.err)
+			if tt.top == "" {
+				assert.Nil(t, st)
+				return
+			}
+			stack, ok := st.([]map[string]string)
+			require.True(t, ok)
+			require.NotEmpty(t, stack)
+			assert.Equal(t, tt.top, stack[0]["func"])
+			assert.Equal(t, "errors_test.go", stack[0]["source"])
+		})
+	}
+}
+
+func TestMarshalErrorChain(t *testing.T) {
+	base := fmt.Errorf("base")
+	kerr := kerrors.NotFound("USER_NOT_FOUND", "no such user").
+		WithMetadata(map[string]string{"id": "42"}).
+		WithCause(base)
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "plain", err: base, want: `null`},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("outer: %w", base),
+			want: `[{"msg":"outer: base","type":"*fmt.wrapError"},{"msg":"base","type":"*errors.errorString"}]`,
+		},
+		{
+			name: "joined",
+			err:  joinError{base, fmt.Errorf("b: %w", base)},
+			want: `[{"errors":[[{"msg":"base","type":"*errors.errorString"}],[{"msg":"b: base","type":"*fmt.wrapError"},{"msg":"base","type":"*errors.errorString"}]],"msg":"joined","type":"slog.joinError"}]`,
+		},
+		{
+			name: "kratos",
+			err:  kerr,
+			want: `[{"code":404,"metadata":{"id":"42"},"msg":"` + kerr.Error() + `","reason":"USER_NOT_FOUND","type":"*errors.Error"},{"msg":"base","type":"*errors.errorString"}]`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(MarshalErrorChain(tt.err))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(b))
+		})
+	}
+}
+
+func TestZerolog_errorChain(t *testing.T) {
+	buf := new(bytes.Buffer)
+	z := newZerolog(buf).WithStack()
+	z.Log(LevelError, fmt.Errorf("query: %w", newStackError()))
+
+	var e map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+	assert.Equal(t, "query: origin", e[ErrorFieldName])
+	assert.Len(t, e[ErrorChainFieldName], 2)
+
+	stack, ok := e[ErrorStackFieldName].([]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "newStackError", stack[0].(map[string]interface{})["func"])
+}
+
+func TestZerolog_kratosError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want map[string]interface{}
+	}{
+		{
+			name: "kratos",
+			err:  kerrors.BadRequest("INVALID_NAME", "bad name").WithMetadata(map[string]string{"field": "name"}),
+			want: map[string]interface{}{
+				ErrorCodeFieldName:     float64(400),
+				ErrorReasonFieldName:   "INVALID_NAME",
+				ErrorMetadataFieldName: map[string]interface{}{"field": "name"},
+			},
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("create: %w", kerrors.Conflict("EXISTS", "exists")),
+			want: map[string]interface{}{
+				ErrorCodeFieldName:   float64(409),
+				ErrorReasonFieldName: "EXISTS",
+			},
+		},
+		{name: "plain", err: fmt.Errorf("plain")},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			newZerolog(buf).Log(LevelError, tt.err)
+
+			var e map[string]interface{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+			for _, k := range []string{ErrorCodeFieldName, ErrorReasonFieldName, ErrorMetadataFieldName} {
+				assert.Equal(t, tt.want[k], e[k], k)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package slog
+
+import (
+	"time"
+
+	zlog "github.com/rs/zerolog"
+)
+
+// Event is a log event built field by field, it is written by Msg, Msgf or
+// Send, the caller reported is the function calling them. The events of
+// disabled levels are no-ops, so that typed fields are added without
+// allocation:
+//
+//	logger.InfoEvent().Str("user", name).Int("attempt", n).Err(err).Msg("login")
+type Event struct {
+	e *zlog.Event
+}
+
+// newEvent starts an Event of level lv, a no-op one if lv is disabled.
+func (z *zerolog) newEvent(lv Level) Event {
+	if !z.Enabled(lv) {
+		return Event{}
+	}
+	return Event{z.event(lv)}
+}
+
+// Enabled reports whether the event will be written.
+func (e Event) Enabled() bool {
+	return e.e != nil
+}
+
+// Str adds the field key with the string val.
+func (e Event) Str(key, val string) Event {
+	e.e.Str(key, val)
+	return e
+}
+
+// Strs adds the field key with the strings vals.
+func (e Event) Strs(key string, vals []string) Event {
+	e.e.Strs(key, vals)
+	return e
+}
+
+// Int adds the field key with the int val.
+func (e Event) Int(key string, val int) Event {
+	e.e.Int(key, val)
+	return e
+}
+
+// Int64 adds the field key with the int64 val.
+func (e Event) Int64(key string, val int64) Event {
+	e.e.Int64(key, val)
+	return e
+}
+
+// Uint64 adds the field key with the uint64 val.
+func (e Event) Uint64(key string, val uint64) Event {
+	e.e.Uint64(key, val)
+	return e
+}
+
+// Float64 adds the field key with the float64 val.
+func (e Event) Float64(key string, val float64) Event {
+	e.e.Float64(key, val)
+	return e
+}
+
+// Bool adds the field key with the bool val.
+func (e Event) Bool(key string, val bool) Event {
+	e.e.Bool(key, val)
+	return e
+}
+
+// Dur adds the field key with the duration val, in DurationFieldUnit.
+func (e Event) Dur(key string, val time.Duration) Event {
+	e.e.Dur(key, val)
+	return e
+}
+
+// Time adds the field key with the time val, in TimeFieldFormat.
+func (e Event) Time(key string, val time.Time) Event {
+	e.e.Time(key, val)
+	return e
+}
+
+// Interface adds the field key with val, a Lazy val is computed only if the
+// event is enabled.
+func (e Event) Interface(key string, val interface{}) Event {
+	if e.e != nil {
+		appendField(e.e, key, val)
+	}
+	return e
+}
+
+// Fields adds the key/value pairs of kvs, a key left without value gets a
+// null value.
+func (e Event) Fields(kvs ...interface{}) Event {
+	if e.e == nil {
+		return e
+	}
+	if len(kvs)%2 == 1 {
+		kvs = append(kvs[:len(kvs):len(kvs)], nil)
+	}
+	appendFields(e.e, kvs)
+	return e
+}
+
+// Err adds err as Log does, with its stack, chain and kratos error fields.
+func (e Event) Err(err error) Event {
+	if e.e == nil || err == nil {
+		return e
+	}
+	e.e.Err(err)
+	errorFields(e.e, err)
+	if ErrorChainMarshaler != nil {
+		if chain := ErrorChainMarshaler(err); chain != nil {
+			e.e.Interface(ErrorChainFieldName, chain)
+		}
+	}
+	return e
+}
+
+// Msg writes the event with the message msg.
+func (e Event) Msg(msg string) {
+	e.e.Msg(msg)
+}
+
+// Msgf writes the event with the message formatted from format and v.
+func (e Event) Msgf(format string, v ...interface{}) {
+	e.e.Msgf(format, v...)
+}
+
+// Send writes the event without message.
+func (e Event) Send() {
+	e.e.Send()
+}
@@ -0,0 +1,84 @@
+package slog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvent(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := &Helper{log: newZerolog(buf)}
+
+	l.InfoEvent().
+		Str("user", "alice").
+		Strs("roles", []string{"admin"}).
+		Int("attempt", 2).
+		Int64("id", 42).
+		Uint64("size", 7).
+		Float64("ratio", 0.5).
+		Bool("ok", true).
+		Dur("took", 1500*time.Millisecond).
+		Interface("lazy", Lazy(func() interface{} { return "computed" })).
+		Fields("k", "v", "dangling").
+		Msg("login")
+
+	assert.JSONEq(t, `{
+		"level":"info","user":"alice","roles":["admin"],"attempt":2,"id":42,"size":7,
+		"ratio":0.5,"ok":true,"took":1500,"lazy":"computed","k":"v","dangling":null,
+		"msg":"login"
+	}`, buf.String())
+}
+
+func TestEvent_err(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := &Helper{log: newZerolog(buf)}
+
+	err := kerrors.NotFound("MISSING", "missing").WithCause(errors.New("no row"))
+	l.ErrorEvent().Err(err).Err(nil).Send()
+
+	var e map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+	assert.Equal(t, "error", e["level"])
+	assert.Equal(t, err.Error(), e[ErrorFieldName])
+	assert.Equal(t, "MISSING", e[ErrorReasonFieldName])
+	assert.Len(t, e[ErrorChainFieldName], 2)
+}
+
+func TestEvent_disabled(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := &Helper{log: newZerolog(buf)}
+	l.SetLevel(LevelWarn)
+
+	calls := 0
+	ev := l.InfoEvent()
+	assert.False(t, ev.Enabled())
+	ev.Str("k", "v").Interface("lazy", Lazy(func() interface{} {
+		calls++
+		return nil
+	})).Err(errors.New("boom")).Msgf("%d", 1)
+	assert.Equal(t, 0, calls)
+	assert.Empty(t, buf.String())
+
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		l.DebugEvent().Str("k", "v").Int("n", 1).Msg("dropped")
+	}))
+}
+
+func TestEvent_caller(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := &Helper{log: newZerolog(buf)}
+	l.WithCaller()
+
+	l.WarnEvent().Msg("here")
+
+	var e map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+	assert.Regexp(t, `^event_test\.go:\d+$`, e[CallerFieldName])
+}
@@ -0,0 +1,242 @@
+package slog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	httpstatus "github.com/go-kratos/kratos/v2/transport/http/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// UnaryServerInterceptor returns a grpc interceptor logging each unary call
+// handled by the server. The context of the handler carries a child of
+// logger with the method of the call, see FromContext.
+func UnaryServerInterceptor(logger FullLogger, opts ...MiddlewareOption) grpc.UnaryServerInterceptor {
+	o := newMiddlewareOptions(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if o.excluded(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		start := time.Now()
+		ctx = WithLogger(ctx, logger.Clone().WithFields("method", info.FullMethod))
+		reply, err := handler(ctx, req)
+
+		c := &grpcCall{kind: "server", method: info.FullMethod, peer: grpcPeer(ctx), start: start, err: err}
+		c.request(o, req)
+		if err == nil {
+			c.response(o, reply)
+		}
+		c.log(logger, o)
+		return reply, err
+	}
+}
+
+// StreamServerInterceptor returns a grpc interceptor logging each stream
+// handled by the server. The context of the stream carries a child of
+// logger with the method of the stream, see FromContext.
+func StreamServerInterceptor(logger FullLogger, opts ...MiddlewareOption) grpc.StreamServerInterceptor {
+	o := newMiddlewareOptions(opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if o.excluded(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		c := &grpcCall{kind: "server", method: info.FullMethod, start: time.Now(), peer: grpcPeer(ss.Context())}
+		ctx := WithLogger(ss.Context(), logger.Clone().WithFields("method", info.FullMethod))
+		c.err = handler(srv, &serverStream{ServerStream: ss, ctx: ctx, call: c, o: o})
+		c.log(logger, o)
+		return c.err
+	}
+}
+
+// UnaryClientInterceptor returns a grpc interceptor logging each unary call
+// sent by the client.
+func UnaryClientInterceptor(logger FullLogger, opts ...MiddlewareOption) grpc.UnaryClientInterceptor {
+	o := newMiddlewareOptions(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		if o.excluded(method) {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+		c := &grpcCall{kind: "client", method: method, start: time.Now(), peer: cc.Target()}
+		c.err = invoker(ctx, method, req, reply, cc, callOpts...)
+		c.request(o, req)
+		if c.err == nil {
+			c.response(o, reply)
+		}
+		c.log(logger, o)
+		return c.err
+	}
+}
+
+// StreamClientInterceptor returns a grpc interceptor logging each stream
+// opened by the client, once the stream ends.
+func StreamClientInterceptor(logger FullLogger, opts ...MiddlewareOption) grpc.StreamClientInterceptor {
+	o := newMiddlewareOptions(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if o.excluded(method) {
+			return streamer(ctx, desc, cc, method, callOpts...)
+		}
+		c := &grpcCall{kind: "client", method: method, start: time.Now(), peer: cc.Target()}
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			c.err = err
+			c.log(logger, o)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, call: c, o: o, logger: logger}, nil
+	}
+}
+
+// grpcCall collects what is logged about a call.
+type grpcCall struct {
+	kind      string
+	method    string
+	peer      string
+	start     time.Time
+	err       error
+	reqBytes  int
+	respBytes int
+	reqs      []json.RawMessage
+	resps     []json.RawMessage
+}
+
+func (c *grpcCall) request(o *middlewareOptions, m interface{}) {
+	c.reqBytes += messageSize(m)
+	if o.payloads {
+		c.reqs = append(c.reqs, renderMessage(m, o.redact))
+	}
+}
+
+func (c *grpcCall) response(o *middlewareOptions, m interface{}) {
+	c.respBytes += messageSize(m)
+	if o.payloads {
+		c.resps = append(c.resps, renderMessage(m, o.redact))
+	}
+}
+
+func (c *grpcCall) log(logger FullLogger, o *middlewareOptions) {
+	code := status.Code(c.err)
+	kvs := []interface{}{
+		"kind", c.kind,
+		"component", "grpc",
+		"method", c.method,
+		"code", code.String(),
+		"latency", time.Since(c.start).Seconds(),
+		"request_bytes", c.reqBytes,
+		"response_bytes", c.respBytes,
+		"peer", c.peer,
+	}
+	if o.payloads {
+		kvs = append(kvs, "request", payload(c.reqs), "response", payload(c.resps))
+	}
+	if c.err != nil {
+		kvs = append(kvs, c.err)
+	}
+	logger.Log(o.level(httpstatus.FromGRPCCode(code)), kvs...)
+}
+
+// payload returns the single message of a unary call, or all the messages
+// of a stream.
+func payload(msgs []json.RawMessage) interface{} {
+	if len(msgs) == 1 {
+		return msgs[0]
+	}
+	return msgs
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	call *grpcCall
+	o    *middlewareOptions
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.call.request(s.o, m)
+	}
+	return err
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.call.response(s.o, m)
+	}
+	return err
+}
+
+// clientStream logs the stream once it ends. SendMsg and RecvMsg may be
+// called concurrently, mu guards the call.
+type clientStream struct {
+	grpc.ClientStream
+	o      *middlewareOptions
+	logger FullLogger
+
+	mu   sync.Mutex
+	call *grpcCall
+	once sync.Once
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	switch err {
+	case nil:
+		s.mu.Lock()
+		s.call.request(s.o, m)
+		s.mu.Unlock()
+	case io.EOF:
+		// the stream ended, its status is returned by RecvMsg.
+	default:
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		s.mu.Lock()
+		s.call.response(s.o, m)
+		s.mu.Unlock()
+	} else {
+		s.finish(err)
+	}
+	return err
+}
+
+// finish logs the stream once, io.EOF is a normal end of stream.
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err != io.EOF {
+			s.call.err = err
+		}
+		s.call.log(s.logger, s.o)
+	})
+}
+
+func grpcPeer(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+func messageSize(m interface{}) int {
+	if pm, ok := m.(proto.Message); ok {
+		return proto.Size(pm)
+	}
+	return 0
+}
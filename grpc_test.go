@@ -0,0 +1,234 @@
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// events decodes the JSON events written so far.
+func (b *syncBuffer) events(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var events []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(b.buf.Bytes()), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var e map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &e))
+		events = append(events, e)
+	}
+	return events
+}
+
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	logged FullLogger
+}
+
+func (s *healthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	s.logged = FromContext(ctx)
+	if req.Service == "missing" {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func (s *healthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	FromContext(stream.Context()).Info("watching")
+	for i := 0; i < 2; i++ {
+		if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newGRPCTest(t *testing.T, server []grpc.ServerOption, dial ...grpc.DialOption) (grpc_health_v1.HealthClient, *healthServer) {
+	ln := bufconn.Listen(1 << 20)
+	hs := new(healthServer)
+	gs := grpc.NewServer(server...)
+	grpc_health_v1.RegisterHealthServer(gs, hs)
+	go gs.Serve(ln)
+	t.Cleanup(gs.Stop)
+
+	dial = append(dial,
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return ln.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.Dial("bufnet", dial...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn), hs
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	buf := new(syncBuffer)
+	logger := &Helper{log: newZerolog(buf)}
+
+	client, hs := newGRPCTest(t, []grpc.ServerOption{
+		grpc.UnaryInterceptor(UnaryServerInterceptor(logger, WithPayloads(), WithRedact("service"))),
+	})
+
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "db"})
+	require.NoError(t, err)
+	assert.NotSame(t, logger, hs.logged)
+
+	_, err = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	events := buf.events(t)
+	require.Len(t, events, 2)
+
+	ok := events[0]
+	assert.Equal(t, "info", ok["level"])
+	assert.Equal(t, "server", ok["kind"])
+	assert.Equal(t, "/grpc.health.v1.Health/Check", ok["method"])
+	assert.Equal(t, "OK", ok["code"])
+	assert.Equal(t, float64(4), ok["request_bytes"])
+	assert.Equal(t, float64(2), ok["response_bytes"])
+	assert.Equal(t, map[string]interface{}{"service": redactedValue}, ok["request"])
+	assert.Equal(t, map[string]interface{}{"status": "SERVING"}, ok["response"])
+	assert.Contains(t, ok, "peer")
+
+	failed := events[1]
+	assert.Equal(t, "warn", failed["level"])
+	assert.Equal(t, "NotFound", failed["code"])
+	assert.Contains(t, failed[ErrorFieldName], "unknown service")
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	buf := new(syncBuffer)
+	logger := &Helper{log: newZerolog(buf)}
+
+	client, _ := newGRPCTest(t, []grpc.ServerOption{
+		grpc.StreamInterceptor(StreamServerInterceptor(logger)),
+	})
+
+	stream, err := client.Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+
+	assert.Eventually(t, func() bool { return len(buf.events(t)) == 2 }, time.Second, 10*time.Millisecond)
+	events := buf.events(t)
+	assert.Equal(t, "watching", events[0]["msg"])
+	assert.Equal(t, "/grpc.health.v1.Health/Watch", events[0]["method"])
+	assert.Equal(t, "OK", events[1]["code"])
+	assert.Equal(t, float64(4), events[1]["response_bytes"])
+}
+
+func TestClientInterceptors(t *testing.T) {
+	buf := new(syncBuffer)
+	logger := &Helper{log: newZerolog(buf)}
+
+	client, _ := newGRPCTest(t, nil,
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(logger, WithExcludes("/grpc.health.v1.Health/Ignored"))),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(logger)),
+	)
+
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "db"})
+	require.NoError(t, err)
+
+	stream, err := client.Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+
+	events := buf.events(t)
+	require.Len(t, events, 2)
+	assert.Equal(t, "client", events[0]["kind"])
+	assert.Equal(t, "bufnet", events[0]["peer"])
+	assert.Equal(t, float64(2), events[0]["response_bytes"])
+	assert.Equal(t, "/grpc.health.v1.Health/Watch", events[1]["method"])
+	assert.Equal(t, "OK", events[1]["code"])
+	assert.NotContains(t, events[1], ErrorFieldName)
+}
+
+// fakeClientStream ends after n messages in each direction.
+type fakeClientStream struct {
+	grpc.ClientStream
+	sent, recv int32
+	n          int32
+}
+
+func (s *fakeClientStream) SendMsg(m interface{}) error {
+	if atomic.AddInt32(&s.sent, 1) > s.n {
+		return status.Error(codes.Unavailable, "closed")
+	}
+	return nil
+}
+
+func (s *fakeClientStream) RecvMsg(m interface{}) error {
+	if atomic.AddInt32(&s.recv, 1) > s.n {
+		return io.EOF
+	}
+	return nil
+}
+
+func TestStreamClientInterceptor_concurrent(t *testing.T) {
+	buf := new(syncBuffer)
+	logger := &Helper{log: newZerolog(buf)}
+	conn, err := grpc.Dial("bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{n: 100}, nil
+	}
+	cs, err := StreamClientInterceptor(logger, WithPayloads())(context.Background(), &grpc.StreamDesc{}, conn, "/test/Chat", streamer)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for cs.SendMsg(&grpc_health_v1.HealthCheckRequest{Service: "db"}) == nil {
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for cs.RecvMsg(new(grpc_health_v1.HealthCheckResponse)) == nil {
+		}
+	}()
+	wg.Wait()
+
+	events := buf.events(t)
+	require.Len(t, events, 1, "the stream is logged once")
+	assert.Equal(t, "/test/Chat", events[0]["method"])
+}
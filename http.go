@@ -0,0 +1,241 @@
+package slog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HTTPFormatJSON sends a batch as a JSON array of events.
+	HTTPFormatJSON = "json"
+	// HTTPFormatLoki sends a batch as a Loki push request.
+	HTTPFormatLoki = "loki"
+	// HTTPFormatElasticsearch sends a batch as an Elasticsearch bulk request.
+	HTTPFormatElasticsearch = "elasticsearch"
+)
+
+// httpEncoder renders a batch as a request body and its content type.
+type httpEncoder func(c *Http, batch []record) ([]byte, string)
+
+// httpCheckers check the body of the successful responses, for the formats
+// reporting the events they rejected in the response.
+var httpCheckers = map[string]func(body []byte) error{
+	HTTPFormatElasticsearch: checkElasticsearch,
+}
+
+var httpEncoders = map[string]httpEncoder{
+	HTTPFormatJSON:          encodeJSON,
+	HTTPFormatLoki:          encodeLoki,
+	HTTPFormatElasticsearch: encodeElasticsearch,
+}
+
+// newHTTPWriter returns a writer sending events in batches to an http
+// endpoint.
+func newHTTPWriter(c *Http) (*batchWriter, error) {
+	if c.Url == "" {
+		return nil, fmt.Errorf("http: url is required")
+	}
+	f := strings.ToLower(c.Format)
+	if f == "" {
+		f = HTTPFormatJSON
+	}
+	encode, ok := httpEncoders[f]
+	if !ok {
+		return nil, fmt.Errorf("http: unknown format %q", c.Format)
+	}
+
+	s := &httpSender{
+		url:        c.Url,
+		headers:    c.Headers,
+		gzip:       c.Gzip,
+		maxRetries: orDefault(c.MaxRetries, 3),
+		client:     &http.Client{Timeout: 10 * time.Second},
+		encode: func(batch []record) ([]byte, string) {
+			return encode(c, batch)
+		},
+		check: httpCheckers[f],
+	}
+	w, err := newBatchWriter(batchOptions{
+		batchSize: orDefault(c.BatchSize, 100),
+		maxBuffer: orDefault(c.MaxBuffer, 10000),
+		interval:  time.Duration(orDefault(c.FlushInterval, 1000)) * time.Millisecond,
+		spillDir:  c.SpillDir,
+	}, s.send)
+	if err != nil {
+		return nil, fmt.Errorf("http: %w", err)
+	}
+	return w, nil
+}
+
+// httpSender posts batches to an http endpoint.
+type httpSender struct {
+	url        string
+	headers    map[string]string
+	gzip       bool
+	maxRetries int
+	client     *http.Client
+	encode     func(batch []record) ([]byte, string)
+	check      func(body []byte) error
+}
+
+// send posts a batch, retrying with exponential backoff.
+func (s *httpSender) send(batch []record) error {
+	body, contentType := s.encode(batch)
+	encoding := ""
+	if s.gzip {
+		var b bytes.Buffer
+		zw := gzip.NewWriter(&b)
+		zw.Write(body)
+		zw.Close()
+		body, encoding = b.Bytes(), "gzip"
+	}
+	var temporary bool
+	err := retry(s.maxRetries, func() (bool, error) {
+		var err error
+		temporary, err = s.post(body, contentType, encoding)
+		return temporary, err
+	})
+	if err != nil && !temporary {
+		return rejectedError{err}
+	}
+	return err
+}
+
+func (s *httpSender) post(body []byte, contentType, encoding string) (temporary bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("http: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("http: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		if s.check == nil {
+			io.Copy(io.Discard, resp.Body)
+			return false, nil
+		}
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return true, fmt.Errorf("http: %w", err)
+		}
+		return false, s.check(b)
+	}
+	io.Copy(io.Discard, resp.Body)
+	err = fmt.Errorf("http: %s returned %s", s.url, resp.Status)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+func encodeJSON(_ *Http, batch []record) ([]byte, string) {
+	var b bytes.Buffer
+	b.WriteByte('[')
+	for i, r := range batch {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(r.Event)
+	}
+	b.WriteByte(']')
+	return b.Bytes(), "application/json"
+}
+
+// encodeLoki renders a Loki push request with one stream per level.
+func encodeLoki(c *Http, batch []record) ([]byte, string) {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+
+	var streams []*stream
+	byLevel := make(map[Level]*stream)
+	for _, r := range batch {
+		s, ok := byLevel[r.Level]
+		if !ok {
+			labels := make(map[string]string, len(c.Labels)+1)
+			for k, v := range c.Labels {
+				labels[k] = v
+			}
+			labels[LevelFieldName] = strings.ToLower(r.Level.String())
+			s = &stream{Stream: labels}
+			byLevel[r.Level] = s
+			streams = append(streams, s)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(r.Time, 10), string(r.Event)})
+	}
+
+	b, _ := json.Marshal(map[string]interface{}{"streams": streams})
+	return b, "application/json"
+}
+
+// encodeElasticsearch renders an Elasticsearch bulk request.
+func encodeElasticsearch(c *Http, batch []record) ([]byte, string) {
+	index := c.Index
+	if index == "" {
+		index = "logs"
+	}
+	action, _ := json.Marshal(map[string]interface{}{"index": map[string]string{"_index": index}})
+
+	var b bytes.Buffer
+	for _, r := range batch {
+		b.Write(action)
+		b.WriteByte('\n')
+		b.Write(r.Event)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), "application/x-ndjson"
+}
+
+// checkElasticsearch reports the events rejected by a bulk request, the
+// request succeeds even if some or all of its events are not indexed.
+// They are not sent again, the others were indexed.
+func checkElasticsearch(body []byte) error {
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("http: elasticsearch: invalid bulk response: %w", err)
+	}
+	if !resp.Errors {
+		return nil
+	}
+	var (
+		rejected int
+		first    string
+	)
+	for _, item := range resp.Items {
+		for _, result := range item {
+			if result.Error == nil {
+				continue
+			}
+			if rejected == 0 {
+				first = fmt.Sprintf("%d %s: %s", result.Status, result.Error.Type, result.Error.Reason)
+			}
+			rejected++
+		}
+	}
+	return fmt.Errorf("http: elasticsearch rejected %d of %d events, first: %s", rejected, len(resp.Items), first)
+}
@@ -0,0 +1,258 @@
+package slog
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	zlog "github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collector is an http endpoint recording the bodies it receives.
+type collector struct {
+	mu     sync.Mutex
+	bodies []string
+	header http.Header
+	fail   int32 // status returned while non zero
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if status := atomic.LoadInt32(&c.fail); status != 0 {
+		w.WriteHeader(int(status))
+		return
+	}
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	b, _ := io.ReadAll(body)
+	c.mu.Lock()
+	c.bodies = append(c.bodies, string(b))
+	c.header = r.Header.Clone()
+	c.mu.Unlock()
+}
+
+func (c *collector) received() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.bodies...)
+}
+
+func Test_httpWriter_json(t *testing.T) {
+	c := new(collector)
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	w, err := newHTTPWriter(&Http{Url: srv.URL, BatchSize: 2, FlushInterval: 60000, Gzip: true, Headers: map[string]string{"X-Token": "secret"}})
+	require.NoError(t, err)
+
+	w.WriteLevel(zlog.InfoLevel, []byte(`{"n":1}`+"\n"))
+	w.WriteLevel(zlog.InfoLevel, []byte(`{"n":2}`+"\n"))
+	assert.Eventually(t, func() bool { return len(c.received()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, `[{"n":1},{"n":2}]`, c.received()[0])
+	assert.Equal(t, "secret", c.header.Get("X-Token"))
+
+	// close flushes the partial batch.
+	w.WriteLevel(zlog.InfoLevel, []byte(`{"n":3}`))
+	require.NoError(t, w.Close())
+	assert.Equal(t, `[{"n":3}]`, c.received()[1])
+}
+
+func Test_httpWriter_formats(t *testing.T) {
+	batch := []record{
+		{Level: LevelInfo, Time: 1, Event: json.RawMessage(`{"msg":"a"}`)},
+		{Level: LevelError, Time: 2, Event: json.RawMessage(`{"msg":"b"}`)},
+	}
+
+	body, contentType := encodeLoki(&Http{Labels: map[string]string{"app": "demo"}}, batch)
+	assert.Equal(t, "application/json", contentType)
+	assert.JSONEq(t, `{"streams":[
+		{"stream":{"app":"demo","level":"info"},"values":[["1","{\"msg\":\"a\"}"]]},
+		{"stream":{"app":"demo","level":"error"},"values":[["2","{\"msg\":\"b\"}"]]}
+	]}`, string(body))
+
+	body, contentType = encodeElasticsearch(&Http{}, batch)
+	assert.Equal(t, "application/x-ndjson", contentType)
+	assert.Equal(t, `{"index":{"_index":"logs"}}`+"\n"+`{"msg":"a"}`+"\n"+`{"index":{"_index":"logs"}}`+"\n"+`{"msg":"b"}`+"\n", string(body))
+}
+
+func Test_httpWriter_retry(t *testing.T) {
+	defer func(d time.Duration) { retryBackoff = d }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	w, err := newHTTPWriter(&Http{Url: srv.URL})
+	require.NoError(t, err)
+	defer w.Close()
+
+	assert.NoError(t, w.send([]record{{Event: json.RawMessage(`{}`)}}))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// client errors are not retried.
+	atomic.StoreInt32(&calls, 0)
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	assert.Error(t, w.send([]record{{Event: json.RawMessage(`{}`)}}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func Test_httpWriter_spill(t *testing.T) {
+	defer func(d time.Duration) { retryBackoff = d }(retryBackoff)
+	retryBackoff = time.Millisecond
+	defer func(h func(error)) { ErrorHandler = h }(ErrorHandler)
+	ErrorHandler = func(error) {}
+
+	c := &collector{fail: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	w, err := newHTTPWriter(&Http{Url: srv.URL, SpillDir: dir, MaxRetries: 1, FlushInterval: 10})
+	require.NoError(t, err)
+	defer w.Close()
+
+	w.WriteLevel(zlog.InfoLevel, []byte(`{"msg":"offline"}`))
+	assert.Eventually(t, func() bool {
+		entries, _ := os.ReadDir(dir)
+		return len(entries) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	atomic.StoreInt32(&c.fail, 0)
+	assert.Eventually(t, func() bool {
+		entries, _ := os.ReadDir(dir)
+		return len(entries) == 0 && len(c.received()) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, `[{"msg":"offline"}]`, c.received()[0])
+}
+
+func Test_httpWriter_spillOverflow(t *testing.T) {
+	defer func(h func(error)) { ErrorHandler = h }(ErrorHandler)
+	ErrorHandler = func(error) {}
+
+	c := &collector{fail: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	w, err := newHTTPWriter(&Http{Url: srv.URL, SpillDir: dir, MaxBuffer: 2, BatchSize: 10, FlushInterval: 60000})
+	require.NoError(t, err)
+	defer w.Close()
+
+	for _, n := range []string{"1", "2", "3"} {
+		w.WriteLevel(zlog.InfoLevel, []byte(`{"n":`+n+`}`))
+	}
+	// the oldest events are written to disk by the background goroutine.
+	assert.Eventually(t, func() bool {
+		names, _ := filepath.Glob(filepath.Join(dir, "*.ndjson"))
+		return len(names) > 0
+	}, time.Second, 10*time.Millisecond)
+
+	atomic.StoreInt32(&c.fail, 0)
+	w.Close()
+	assert.Contains(t, strings.Join(c.received(), ""), `{"n":1}`)
+}
+
+func Test_httpWriter_invalidSpill(t *testing.T) {
+	var reported []error
+	var mu sync.Mutex
+	defer func(h func(error)) { ErrorHandler = h }(ErrorHandler)
+	ErrorHandler = func(err error) {
+		mu.Lock()
+		reported = append(reported, err)
+		mu.Unlock()
+	}
+
+	c := new(collector)
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "0000000000000000001-000001.ndjson")
+	require.NoError(t, os.WriteFile(bad, []byte("{not json"), 0o644))
+
+	w, err := newHTTPWriter(&Http{Url: srv.URL, SpillDir: dir, FlushInterval: 10})
+	require.NoError(t, err)
+	w.WriteLevel(zlog.InfoLevel, []byte(`{}`))
+	require.NoError(t, w.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, reported, 1)
+	assert.Contains(t, reported[0].Error(), bad)
+	_, err = os.Stat(bad + ".invalid")
+	assert.NoError(t, err, "the file is kept")
+}
+
+func Test_httpWriter_elasticsearchErrors(t *testing.T) {
+	var body atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body.Load().(string))
+	}))
+	defer srv.Close()
+
+	w, err := newHTTPWriter(&Http{Url: srv.URL, Format: HTTPFormatElasticsearch})
+	require.NoError(t, err)
+	defer w.Close()
+	batch := []record{{Event: json.RawMessage(`{"a":1}`)}, {Event: json.RawMessage(`{"a":"x"}`)}}
+
+	body.Store(`{"errors":false,"items":[{"index":{"status":201}},{"index":{"status":201}}]}`)
+	assert.NoError(t, w.send(batch))
+
+	body.Store(`{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [a]"}}}]}`)
+	err = w.send(batch)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rejected 1 of 2 events")
+	assert.Contains(t, err.Error(), "mapper_parsing_exception")
+	assert.ErrorAs(t, err, new(rejectedError), "not spilled")
+}
+
+func Test_httpWriter_maxBuffer(t *testing.T) {
+	var dropped []error
+	defer func(h func(error)) { ErrorHandler = h }(ErrorHandler)
+	ErrorHandler = func(err error) { dropped = append(dropped, err) }
+
+	w, err := newHTTPWriter(&Http{Url: "http://127.0.0.1:0", MaxBuffer: 2, BatchSize: 10, FlushInterval: 60000})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		w.WriteLevel(zlog.InfoLevel, []byte(`{}`))
+	}
+	w.mu.Lock()
+	assert.Len(t, w.buf, 2)
+	w.buf = nil
+	w.mu.Unlock()
+	w.Close()
+	require.Len(t, dropped, 1)
+	assert.True(t, strings.Contains(dropped[0].Error(), "dropped 1 events"))
+}
+
+func Test_newHTTPWriter_invalid(t *testing.T) {
+	_, err := newHTTPWriter(&Http{})
+	assert.Error(t, err)
+	_, err = newHTTPWriter(&Http{Url: "http://localhost", Format: "nope"})
+	assert.Error(t, err)
+}
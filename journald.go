@@ -0,0 +1,188 @@
+package slog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	zlog "github.com/rs/zerolog"
+)
+
+// journalSocket is the default path of the systemd journal socket.
+const journalSocket = "/run/systemd/journal/socket"
+
+var (
+	_ zlog.LevelWriter = (*journaldWriter)(nil)
+	_ io.Closer        = (*journaldWriter)(nil)
+)
+
+// journaldWriter sends each event to the systemd journal using its native
+// protocol, keeping the fields of the event as journal fields.
+type journaldWriter struct {
+	socket     string
+	identifier string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newJournaldWriter(c *Journald) *journaldWriter {
+	w := &journaldWriter{
+		socket:     c.Socket,
+		identifier: c.Identifier,
+	}
+	if w.socket == "" {
+		w.socket = journalSocket
+	}
+	if w.identifier == "" {
+		w.identifier = filepath.Base(os.Args[0])
+	}
+	return w
+}
+
+func (w *journaldWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zlog.NoLevel, p)
+}
+
+func (w *journaldWriter) WriteLevel(l zlog.Level, p []byte) (int, error) {
+	msg, err := w.encode(levelOf(l), p)
+	if err != nil {
+		return 0, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i := 0; i < 2; i++ {
+		if w.conn == nil {
+			if w.conn, err = net.Dial("unixgram", w.socket); err != nil {
+				return 0, err
+			}
+		}
+		if _, err = w.conn.Write(msg); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+func (w *journaldWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// journalTrustedFields are the journal fields written by encode, the event
+// fields named alike are prefixed with journalFieldPrefix.
+var journalTrustedFields = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+}
+
+const journalFieldPrefix = "FIELD_"
+
+// encode converts a JSON event to journal fields. The message, level and
+// caller are mapped to MESSAGE, PRIORITY, CODE_FILE and CODE_LINE, other
+// fields are uppercased, and prefixed when they would take the name of one
+// of these.
+func (w *journaldWriter) encode(lv Level, p []byte) ([]byte, error) {
+	var fields map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	if err := d.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	writeJournalField(&b, "PRIORITY", strconv.Itoa(SyslogSeverity(lv)))
+	writeJournalField(&b, "SYSLOG_IDENTIFIER", w.identifier)
+
+	if msg, ok := fields[MessageFieldName]; ok {
+		writeJournalField(&b, "MESSAGE", journalValue(msg))
+	} else {
+		writeJournalField(&b, "MESSAGE", "")
+	}
+	if caller, ok := fields[CallerFieldName].(string); ok {
+		if i := strings.LastIndexByte(caller, ':'); i > 0 {
+			writeJournalField(&b, "CODE_FILE", caller[:i])
+			writeJournalField(&b, "CODE_LINE", caller[i+1:])
+		} else {
+			writeJournalField(&b, "CODE_FILE", caller)
+		}
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		switch k {
+		case MessageFieldName, LevelFieldName, CallerFieldName:
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := journalFieldName(k)
+		if journalTrustedFields[name] {
+			name = journalFieldPrefix + name
+		}
+		if name != "" {
+			writeJournalField(&b, name, journalValue(fields[k]))
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// journalFieldName converts k to a valid journal field name: uppercase
+// letters, digits and underscores, not starting with an underscore or a
+// digit.
+func journalFieldName(k string) string {
+	name := []byte(strings.ToUpper(k))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	return strings.TrimLeft(string(name), "_0123456789")
+}
+
+func journalValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// writeJournalField writes a field in the journal native format, values
+// containing newlines are length prefixed.
+func writeJournalField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
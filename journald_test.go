@@ -0,0 +1,80 @@
+package slog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_journaldWriter(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	pc, err := net.ListenPacket("unixgram", socket)
+	require.NoError(t, err)
+	defer pc.Close()
+
+	l := New(&Config{Journald: &Journald{Socket: socket, Identifier: "app"}}).WithCaller()
+	l.WithFields("user-id", 7, "note", "multi\nline", "message", "field", "priority", 1, "code_line", 2).Warn("hello")
+
+	buf := make([]byte, 4096)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+
+	fields := parseJournal(t, buf[:n])
+	assert.Equal(t, "4", fields["PRIORITY"])
+	assert.Equal(t, "app", fields["SYSLOG_IDENTIFIER"])
+	assert.Equal(t, "hello", fields["MESSAGE"])
+	assert.Equal(t, "journald_test.go", fields["CODE_FILE"])
+	assert.NotEmpty(t, fields["CODE_LINE"])
+	assert.Equal(t, "7", fields["USER_ID"])
+	assert.Equal(t, "multi\nline", fields["NOTE"])
+	assert.NotContains(t, fields, "LEVEL")
+	assert.Equal(t, "field", fields["FIELD_MESSAGE"])
+	assert.Equal(t, "1", fields["FIELD_PRIORITY"])
+	assert.Equal(t, "2", fields["FIELD_CODE_LINE"])
+}
+
+func Test_journalFieldName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"foo", "FOO"},
+		{"user.id", "USER_ID"},
+		{"_private", "PRIVATE"},
+		{"1st", "ST"},
+		{"__", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, journalFieldName(tt.in), tt.in)
+	}
+}
+
+// parseJournal decodes a message in the journal native format.
+func parseJournal(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		require.True(t, i >= 0)
+		line := string(b[:i])
+		b = b[i+1:]
+		if k, v, ok := strings.Cut(line, "="); ok {
+			require.NotContains(t, fields, k, "duplicate field")
+			fields[k] = v
+			continue
+		}
+		require.NotContains(t, fields, line, "duplicate field")
+		size := binary.LittleEndian.Uint64(b[:8])
+		fields[line] = string(b[8 : 8+size])
+		b = b[8+size+1:]
+	}
+	return fields
+}
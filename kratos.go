@@ -0,0 +1,88 @@
+package slog
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"google.golang.org/grpc/peer"
+)
+
+// KratosServer returns a kratos middleware logging each request handled by
+// the server. The context of the handler carries a child of logger with
+// the kind and operation of the request, see FromContext.
+func KratosServer(logger FullLogger, opts ...MiddlewareOption) middleware.Middleware {
+	o := newMiddlewareOptions(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok || o.excluded(tr.Operation()) {
+				return handler(ctx, req)
+			}
+			start := time.Now()
+			ctx = WithLogger(ctx, logger.Clone().WithFields(
+				"component", tr.Kind().String(),
+				"operation", tr.Operation(),
+			))
+			reply, err := handler(ctx, req)
+			logRequest(logger, o, "server", tr, serverPeer(ctx, tr), start, err)
+			return reply, err
+		}
+	}
+}
+
+// KratosClient returns a kratos middleware logging each request sent by
+// the client.
+func KratosClient(logger FullLogger, opts ...MiddlewareOption) middleware.Middleware {
+	o := newMiddlewareOptions(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromClientContext(ctx)
+			if !ok || o.excluded(tr.Operation()) {
+				return handler(ctx, req)
+			}
+			start := time.Now()
+			ctx = WithLogger(ctx, logger.Clone().WithFields(
+				"component", tr.Kind().String(),
+				"operation", tr.Operation(),
+			))
+			reply, err := handler(ctx, req)
+			logRequest(logger, o, "client", tr, tr.Endpoint(), start, err)
+			return reply, err
+		}
+	}
+}
+
+func logRequest(logger FullLogger, o *middlewareOptions, kind string, tr transport.Transporter, peer string, start time.Time, err error) {
+	code, reason := http.StatusOK, ""
+	if se := errors.FromError(err); se != nil {
+		code, reason = int(se.Code), se.Reason
+	}
+	kvs := []interface{}{
+		"kind", kind,
+		"component", tr.Kind().String(),
+		"operation", tr.Operation(),
+		"code", code,
+		"reason", reason,
+		"latency", time.Since(start).Seconds(),
+		"peer", peer,
+	}
+	if err != nil {
+		kvs = append(kvs, err)
+	}
+	logger.Log(o.level(code), kvs...)
+}
+
+// serverPeer returns the address of the client of a server request.
+func serverPeer(ctx context.Context, tr transport.Transporter) string {
+	if ht, ok := tr.(interface{ Request() *http.Request }); ok && ht.Request() != nil {
+		return ht.Request().RemoteAddr
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testTransport struct {
+	kind      transport.Kind
+	endpoint  string
+	operation string
+	request   *http.Request
+}
+
+func (tr *testTransport) Kind() transport.Kind            { return tr.kind }
+func (tr *testTransport) Endpoint() string                { return tr.endpoint }
+func (tr *testTransport) Operation() string               { return tr.operation }
+func (tr *testTransport) RequestHeader() transport.Header { return nil }
+func (tr *testTransport) ReplyHeader() transport.Header   { return nil }
+func (tr *testTransport) Request() *http.Request          { return tr.request }
+
+func TestKratosServer(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		opts  []MiddlewareOption
+		level string
+		code  float64
+	}{
+		{name: "ok", level: "info", code: 200},
+		{name: "client error", err: errors.BadRequest("INVALID", "bad"), level: "warn", code: 400},
+		{name: "server error", err: errors.InternalServer("BOOM", "boom"), level: "error", code: 500},
+		{
+			name:  "custom level",
+			err:   errors.NotFound("MISSING", "missing"),
+			opts:  []MiddlewareOption{WithStatusLevel(func(int) Level { return LevelDebug })},
+			level: "debug",
+			code:  404,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.level == "debug" && !debugEnabled {
+				t.Skip("debug events are dropped with slog_nodebug")
+			}
+			buf := new(bytes.Buffer)
+			logger := &Helper{log: newZerolog(buf)}
+			logger.SetLevel(LevelDebug)
+
+			r, _ := http.NewRequest(http.MethodGet, "/hello", nil)
+			r.RemoteAddr = "10.0.0.1:1234"
+			ctx := transport.NewServerContext(context.Background(), &testTransport{
+				kind:      transport.KindHTTP,
+				operation: "/helloworld.Greeter/SayHello",
+				request:   r,
+			})
+
+			var inner FullLogger
+			h := KratosServer(logger, tt.opts...)(func(ctx context.Context, req interface{}) (interface{}, error) {
+				inner = FromContext(ctx)
+				inner.Info("inside")
+				return "reply", tt.err
+			})
+			reply, err := h(ctx, "req")
+			assert.Equal(t, "reply", reply)
+			assert.Equal(t, tt.err, err)
+			assert.NotSame(t, logger, inner)
+
+			lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+			require.Len(t, lines, 2)
+
+			var child, access map[string]interface{}
+			require.NoError(t, json.Unmarshal(lines[0], &child))
+			assert.Equal(t, "/helloworld.Greeter/SayHello", child["operation"])
+			assert.Equal(t, "inside", child["msg"])
+
+			require.NoError(t, json.Unmarshal(lines[1], &access))
+			assert.Equal(t, tt.level, access["level"])
+			assert.Equal(t, "server", access["kind"])
+			assert.Equal(t, "http", access["component"])
+			assert.Equal(t, tt.code, access["code"])
+			assert.Equal(t, "10.0.0.1:1234", access["peer"])
+			assert.Contains(t, access, "latency")
+		})
+	}
+}
+
+func TestKratosServer_excludes(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := &Helper{log: newZerolog(buf)}
+
+	ctx := transport.NewServerContext(context.Background(), &testTransport{operation: "/healthz"})
+	h := KratosServer(logger, WithExcludes("/healthz"))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	_, err := h(ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, buf.String())
+}
+
+func TestKratosClient(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := &Helper{log: newZerolog(buf)}
+
+	ctx := transport.NewClientContext(context.Background(), &testTransport{
+		kind:      transport.KindGRPC,
+		endpoint:  "discovery:///greeter",
+		operation: "/helloworld.Greeter/SayHello",
+	})
+	h := KratosClient(logger)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.ServiceUnavailable("DOWN", "down")
+	})
+	_, err := h(ctx, nil)
+	assert.Error(t, err)
+
+	var access map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &access))
+	assert.Equal(t, "client", access["kind"])
+	assert.Equal(t, "discovery:///greeter", access["peer"])
+	assert.Equal(t, "DOWN", access["reason"])
+	assert.Equal(t, "error", access["level"])
+}
@@ -31,6 +31,28 @@ type FormatLogger interface {
 	Fatalf(format string, v ...interface{})
 }
 
+// TemplateLogger is a logger interface that output logs with a message
+// template, e.g. "user {user} logged in from {ip}". The message is rendered
+// with the arguments, which are also added as fields named after the
+// placeholders, and the template is added as the "tmpl" field.
+type TemplateLogger interface {
+	Debugt(tmpl string, v ...interface{})
+	Infot(tmpl string, v ...interface{})
+	Warnt(tmpl string, v ...interface{})
+	Errort(tmpl string, v ...interface{})
+	Fatalt(tmpl string, v ...interface{})
+}
+
+// EventLogger is a logger interface that output logs built field by field,
+// see Event.
+type EventLogger interface {
+	DebugEvent() Event
+	InfoEvent() Event
+	WarnEvent() Event
+	ErrorEvent() Event
+	FatalEvent() Event
+}
+
 // Control provides methods to config a logger.
 type Control interface {
 	SetLevel(Level) Control
@@ -47,17 +69,30 @@ type FullLogger interface {
 	KLogger
 	LevelLogger
 	FormatLogger
+	TemplateLogger
+	EventLogger
 	Control
+	Enabled(Level) bool
 	Clone() FullLogger
 	WithTimestamp() FullLogger
 	WithCaller() FullLogger
 	WithCallerWithSkipFrameCount(skipFrameCount int) FullLogger
+	AddCallerSkip(skip int) FullLogger
 	WithStack() FullLogger
 	WithFields(fields ...interface{}) FullLogger
 }
 
 type Level = log.Level
 
+// Lazy is a field value computed only when the event it belongs to is
+// logged, for values too expensive to compute for disabled levels:
+//
+//	logger.Debug("state", slog.Lazy(func() interface{} { return dump() }))
+//
+// A Lazy passed to WithFields is computed for each logged event, one passed
+// as argument of Debugf and the like before formatting.
+type Lazy func() interface{}
+
 const (
 	// LevelDebug is logger debug level.
 	LevelDebug = log.LevelDebug
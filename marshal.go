@@ -0,0 +1,225 @@
+package slog
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	zlog "github.com/rs/zerolog"
+	"google.golang.org/protobuf/proto"
+)
+
+// ObjectMarshaler is implemented by the types encoding themselves as a JSON
+// object when logged, without reflection.
+type ObjectMarshaler interface {
+	MarshalLogObject(e Event)
+}
+
+// ArrayMarshaler is implemented by the types encoding themselves as a JSON
+// array when logged, without reflection.
+type ArrayMarshaler interface {
+	MarshalLogArray(a Array)
+}
+
+// Array is the array of an ArrayMarshaler, built element by element.
+type Array struct {
+	a *zlog.Array
+}
+
+// Str appends the string val.
+func (a Array) Str(val string) Array {
+	a.a.Str(val)
+	return a
+}
+
+// Int appends the int val.
+func (a Array) Int(val int) Array {
+	a.a.Int(val)
+	return a
+}
+
+// Int64 appends the int64 val.
+func (a Array) Int64(val int64) Array {
+	a.a.Int64(val)
+	return a
+}
+
+// Uint64 appends the uint64 val.
+func (a Array) Uint64(val uint64) Array {
+	a.a.Uint64(val)
+	return a
+}
+
+// Float64 appends the float64 val.
+func (a Array) Float64(val float64) Array {
+	a.a.Float64(val)
+	return a
+}
+
+// Bool appends the bool val.
+func (a Array) Bool(val bool) Array {
+	a.a.Bool(val)
+	return a
+}
+
+// Dur appends the duration val, in DurationFieldUnit.
+func (a Array) Dur(val time.Duration) Array {
+	a.a.Dur(val)
+	return a
+}
+
+// Time appends the time val, in TimeFieldFormat.
+func (a Array) Time(val time.Time) Array {
+	a.a.Time(val)
+	return a
+}
+
+// Object appends the object val.
+func (a Array) Object(val ObjectMarshaler) Array {
+	a.a.Object(objectMarshaler{val})
+	return a
+}
+
+// Interface appends val, encoded as a field value.
+func (a Array) Interface(val interface{}) Array {
+	switch v := fieldValue(val).(type) {
+	case zlog.LogObjectMarshaler:
+		a.a.Object(v)
+	case arrayMarshaler:
+		a.a.Interface(arrayJSON(v))
+	default:
+		a.a.Interface(v)
+	}
+	return a
+}
+
+// Object adds the field key with the object val.
+func (e Event) Object(key string, val ObjectMarshaler) Event {
+	e.e.Object(key, objectMarshaler{val})
+	return e
+}
+
+// Array adds the field key with the array val.
+func (e Event) Array(key string, val ArrayMarshaler) Event {
+	e.e.Array(key, arrayMarshaler{val})
+	return e
+}
+
+// objectMarshaler adapts an ObjectMarshaler to zerolog.
+type objectMarshaler struct {
+	m ObjectMarshaler
+}
+
+func (o objectMarshaler) MarshalZerologObject(e *zlog.Event) {
+	o.m.MarshalLogObject(Event{e})
+}
+
+// arrayMarshaler adapts an ArrayMarshaler to zerolog.
+type arrayMarshaler struct {
+	m ArrayMarshaler
+}
+
+func (a arrayMarshaler) MarshalZerologArray(arr *zlog.Array) {
+	a.m.MarshalLogArray(Array{arr})
+}
+
+// arrayJSON encodes an ArrayMarshaler nested in an array, where zerolog
+// only accepts values encoded as JSON.
+type arrayJSON arrayMarshaler
+
+func (a arrayJSON) MarshalJSON() ([]byte, error) {
+	// zerolog encodes arrays only as fields of an event.
+	buf := new(bytes.Buffer)
+	l := zlog.New(buf)
+	l.Log().Array("a", arrayMarshaler(a)).Send()
+
+	var v struct {
+		A json.RawMessage `json:"a"`
+	}
+	if err := json.Unmarshal(eventJSON(buf.Bytes()), &v); err != nil {
+		return nil, err
+	}
+	return v.A, nil
+}
+
+// hasMarshaler reports whether v is encoded by fieldValue rather than as is.
+// The cases are in the order of the conversions of fieldValue.
+func hasMarshaler(v interface{}) bool {
+	switch v.(type) {
+	case ObjectMarshaler, ArrayMarshaler, proto.Message:
+		return true
+	case time.Time, time.Duration, json.Marshaler, error:
+		return false
+	case encoding.TextMarshaler, fmt.Stringer:
+		return true
+	}
+	return false
+}
+
+// fieldValue converts the field value v for zerolog: a Lazy is computed,
+// object and array marshalers are adapted, proto messages are encoded with
+// ProtoMarshalOptions, TextMarshaler and Stringer values are encoded as text
+// unless they encode themselves as JSON. Nil pointers are encoded as null
+// rather than calling their methods.
+func fieldValue(v interface{}) interface{} {
+	v = resolve(v)
+	if !hasMarshaler(v) {
+		return v
+	}
+	if isNilPointer(v) {
+		return nil
+	}
+	switch m := v.(type) {
+	case ObjectMarshaler:
+		return objectMarshaler{m}
+	case ArrayMarshaler:
+		return arrayMarshaler{m}
+	case proto.Message:
+		return marshalProto(ProtoMarshalOptions, m, nil, nil)
+	case encoding.TextMarshaler:
+		b, err := m.MarshalText()
+		if err != nil {
+			return err.Error()
+		}
+		return string(b)
+	case fmt.Stringer:
+		return m.String()
+	}
+	return v
+}
+
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// appendFields adds the key/value pairs of fields to e, with their values
+// converted by fieldValue.
+func appendFields(e *zlog.Event, fields []interface{}) {
+	if isPlain(fields) {
+		e.Fields(fields)
+		return
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, ok := fields[i].(string)
+		if !ok {
+			key = fmt.Sprint(fields[i])
+		}
+		appendField(e, key, fields[i+1])
+	}
+}
+
+// appendField adds the field key to e with val converted by fieldValue.
+func appendField(e *zlog.Event, key string, val interface{}) {
+	switch v := fieldValue(val).(type) {
+	case zlog.LogObjectMarshaler:
+		e.Object(key, v)
+	case zlog.LogArrayMarshaler:
+		e.Array(key, v)
+	default:
+		e.Fields([]interface{}{key, v})
+	}
+}
@@ -0,0 +1,140 @@
+package slog
+
+import (
+	"bytes"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	name  string
+	roles roles
+}
+
+func (u user) MarshalLogObject(e Event) {
+	e.Str("name", u.name).Array("roles", u.roles)
+}
+
+type roles []string
+
+func (r roles) MarshalLogArray(a Array) {
+	for _, s := range r {
+		a.Str(s)
+	}
+}
+
+type point struct{ x, y int }
+
+func (p point) String() string { return "point" }
+
+// jsonUser encodes itself both as JSON and as a log object.
+type jsonUser struct{ name string }
+
+func (u jsonUser) MarshalJSON() ([]byte, error) { return []byte(`"json"`), nil }
+
+func (u jsonUser) MarshalLogObject(e Event) { e.Str("name", u.name) }
+
+type matrix [][]int
+
+func (m matrix) MarshalLogArray(a Array) {
+	for _, row := range m {
+		a.Interface(intRow(row))
+	}
+}
+
+type intRow []int
+
+func (r intRow) MarshalLogArray(a Array) {
+	for _, v := range r {
+		a.Int(v)
+	}
+}
+
+func TestMarshalers(t *testing.T) {
+	alice := user{name: "alice", roles: roles{"admin"}}
+	tests := []struct {
+		name string
+		kvs  []interface{}
+		want string
+	}{
+		{
+			name: "object",
+			kvs:  []interface{}{"login", "user", alice},
+			want: `{"level":"info","msg":"login","user":{"name":"alice","roles":["admin"]}}`,
+		},
+		{
+			name: "array",
+			kvs:  []interface{}{"roles", roles{"a", "b"}},
+			want: `{"level":"info","roles":["a","b"]}`,
+		},
+		{
+			name: "nested array",
+			kvs:  []interface{}{"m", matrix{{1, 2}, {3}}},
+			want: `{"level":"info","m":[[1,2],[3]]}`,
+		},
+		{
+			name: "stringer",
+			kvs:  []interface{}{"p", point{1, 2}},
+			want: `{"level":"info","p":"point"}`,
+		},
+		{
+			name: "text marshaler",
+			kvs:  []interface{}{"ip", net.IPv4(10, 0, 0, 1)},
+			want: `{"level":"info","ip":"10.0.0.1"}`,
+		},
+		{
+			name: "proto",
+			kvs:  []interface{}{"caller", &Caller{Format: CallerFormatFull, Func: true}},
+			want: `{"level":"info","caller":{"format":"full","func":true,"goroutine":false}}`,
+		},
+		{
+			name: "nil pointers",
+			kvs:  []interface{}{"url", (*url.URL)(nil), "ip", (*net.IP)(nil), "caller", (*Caller)(nil)},
+			want: `{"level":"info","url":null,"ip":null,"caller":null}`,
+		},
+		{
+			name: "object and json marshaler",
+			kvs:  []interface{}{"user", jsonUser{name: "bob"}},
+			want: `{"level":"info","user":{"name":"bob"}}`,
+		},
+		{
+			name: "native types",
+			kvs:  []interface{}{"d", time.Second, "t", time.Unix(0, 0).UTC()},
+			want: `{"level":"info","d":1000,"t":"1970-01-01T00:00:00Z"}`,
+		},
+		{
+			name: "lazy",
+			kvs:  []interface{}{"user", Lazy(func() interface{} { return alice })},
+			want: `{"level":"info","user":{"name":"alice","roles":["admin"]}}`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			newZerolog(buf).Log(LevelInfo, tt.kvs...)
+			assert.JSONEq(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestEvent_marshalers(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := &Helper{log: newZerolog(buf)}
+
+	l.InfoEvent().
+		Object("user", user{name: "bob"}).
+		Array("roles", roles{"dev"}).
+		Interface("p", point{}).
+		Interface("url", (*url.URL)(nil)).
+		Fields("m", matrix{{1}}).
+		Send()
+
+	assert.JSONEq(t, `{
+		"level":"info","user":{"name":"bob","roles":[]},"roles":["dev"],"p":"point","url":null,"m":[[1]]
+	}`, buf.String())
+}
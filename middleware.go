@@ -0,0 +1,96 @@
+package slog
+
+import "net/http"
+
+// MiddlewareOption configures the logging middlewares.
+type MiddlewareOption func(*middlewareOptions)
+
+type middlewareOptions struct {
+	level           func(status int) Level
+	excludes        map[string]struct{}
+	accessFormat    string
+	requestIDHeader string
+	payloads        bool
+	redact          map[string]struct{}
+}
+
+// WithStatusLevel sets the function choosing the level of a request from
+// its http status code. default is DefaultStatusLevel.
+func WithStatusLevel(f func(status int) Level) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.level = f
+	}
+}
+
+// WithExcludes disables logging of the given operations or paths, e.g.
+// health checks.
+func WithExcludes(paths ...string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		for _, p := range paths {
+			o.excludes[p] = struct{}{}
+		}
+	}
+}
+
+// WithAccessFormat sets the message of the http access logs, one of
+// AccessFormatNone, AccessFormatCommon or AccessFormatCombined.
+func WithAccessFormat(format string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.accessFormat = format
+	}
+}
+
+// WithRequestIDHeader sets the header carrying the request id.
+// default is "X-Request-Id".
+func WithRequestIDHeader(header string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.requestIDHeader = header
+	}
+}
+
+// WithPayloads logs the request and response messages of grpc calls.
+func WithPayloads() MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.payloads = true
+	}
+}
+
+// WithRedact masks the given protobuf fields, by name, in the logged
+// messages.
+func WithRedact(fields ...string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		for _, f := range fields {
+			o.redact[f] = struct{}{}
+		}
+	}
+}
+
+// DefaultStatusLevel logs server errors at error level, client errors at
+// warn level and other requests at info level.
+func DefaultStatusLevel(status int) Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return LevelError
+	case status >= http.StatusBadRequest:
+		return LevelWarn
+	}
+	return LevelInfo
+}
+
+func newMiddlewareOptions(opts []MiddlewareOption) *middlewareOptions {
+	o := &middlewareOptions{
+		level:           DefaultStatusLevel,
+		excludes:        make(map[string]struct{}),
+		requestIDHeader: "X-Request-Id",
+		redact:          make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *middlewareOptions) excluded(path string) bool {
+	_, ok := o.excludes[path]
+	return ok
+}
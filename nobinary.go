@@ -0,0 +1,12 @@
+//go:build !binary_log
+
+package slog
+
+// binaryLog is true when built with the binary_log tag of zerolog, which
+// then encodes the events as CBOR instead of JSON.
+const binaryLog = false
+
+// eventJSON returns the JSON encoding of the event p.
+func eventJSON(p []byte) []byte {
+	return p
+}
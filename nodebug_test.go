@@ -0,0 +1,32 @@
+//go:build slog_nodebug
+
+package slog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoDebug(t *testing.T) {
+	buf := new(syncBuffer)
+	l := &Helper{log: newZerolog(buf)}
+	l.SetLevel(LevelDebug)
+	defer ReplaceGlobals(l)()
+
+	l.Debug("dropped")
+	l.Debugf("%s", "dropped")
+	l.Log(LevelDebug, "dropped")
+	l.Debugt("{v}", "dropped")
+	l.DebugEvent().Msg("dropped")
+	Debug("dropped")
+	Debugf("%s", "dropped")
+	Debugt("{v}", "dropped")
+	DebugEvent().Msg("dropped")
+	assert.False(t, l.DebugEvent().Enabled())
+	assert.False(t, l.Enabled(LevelDebug))
+	assert.Empty(t, buf.events(t))
+
+	l.Info("kept")
+	assert.Len(t, buf.events(t), 1)
+}
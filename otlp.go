@@ -0,0 +1,297 @@
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// OTLPProtocolHTTP exports logs with OTLP/HTTP in binary protobuf.
+	OTLPProtocolHTTP = "http"
+	// OTLPProtocolGRPC exports logs with OTLP/gRPC.
+	OTLPProtocolGRPC = "grpc"
+)
+
+// otlpScope is the instrumentation scope of the exported records.
+const otlpScope = "github.com/sraphs/slog"
+
+var (
+	// TraceIDFieldNames are the fields exported as the trace id of a record.
+	TraceIDFieldNames = []string{"trace_id", "trace.id"}
+	// SpanIDFieldNames are the fields exported as the span id of a record.
+	SpanIDFieldNames = []string{"span_id", "span.id"}
+)
+
+// otlpWriter exports events as OpenTelemetry log records.
+type otlpWriter struct {
+	*batchWriter
+	conn *grpc.ClientConn
+}
+
+func newOTLPWriter(c *Otlp) (*otlpWriter, error) {
+	if c.Endpoint == "" {
+		return nil, fmt.Errorf("otlp: endpoint is required")
+	}
+
+	resource := otlpResource(c.Resource)
+	maxRetries := orDefault(c.MaxRetries, 3)
+	w := new(otlpWriter)
+
+	var send func(batch []record) error
+	switch strings.ToLower(c.Protocol) {
+	case "", OTLPProtocolHTTP:
+		s := &httpSender{
+			url:        c.Endpoint,
+			headers:    c.Headers,
+			gzip:       c.Gzip,
+			maxRetries: maxRetries,
+			client:     &http.Client{Timeout: 10 * time.Second},
+			encode: func(batch []record) ([]byte, string) {
+				b, _ := proto.Marshal(otlpRequest(resource, batch))
+				return b, "application/x-protobuf"
+			},
+		}
+		send = s.send
+	case OTLPProtocolGRPC:
+		creds := credentials.NewClientTLSFromCert(nil, "")
+		if c.Insecure {
+			creds = insecure.NewCredentials()
+		}
+		conn, err := grpc.Dial(c.Endpoint, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("otlp: %w", err)
+		}
+		w.conn = conn
+		client := collogspb.NewLogsServiceClient(conn)
+		var opts []grpc.CallOption
+		if c.Gzip {
+			opts = append(opts, grpc.UseCompressor(gzip.Name))
+		}
+		send = func(batch []record) error {
+			req := otlpRequest(resource, batch)
+			return retry(maxRetries, func() (bool, error) {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				if len(c.Headers) > 0 {
+					ctx = metadata.NewOutgoingContext(ctx, metadata.New(c.Headers))
+				}
+				_, err := client.Export(ctx, req, opts...)
+				if err != nil {
+					return otlpTemporary(err), fmt.Errorf("otlp: %w", err)
+				}
+				return false, nil
+			})
+		}
+	default:
+		return nil, fmt.Errorf("otlp: unknown protocol %q", c.Protocol)
+	}
+
+	bw, err := newBatchWriter(batchOptions{
+		batchSize: orDefault(c.BatchSize, 512),
+		maxBuffer: orDefault(c.MaxBuffer, 10000),
+		interval:  time.Duration(orDefault(c.FlushInterval, 1000)) * time.Millisecond,
+	}, send)
+	if err != nil {
+		if w.conn != nil {
+			w.conn.Close()
+		}
+		return nil, fmt.Errorf("otlp: %w", err)
+	}
+	w.batchWriter = bw
+	return w, nil
+}
+
+// Close exports the buffered records and closes the connection.
+func (w *otlpWriter) Close() error {
+	w.batchWriter.Close()
+	if w.conn != nil {
+		return w.conn.Close()
+	}
+	return nil
+}
+
+func otlpTemporary(err error) bool {
+	switch status.Code(err) {
+	case codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted,
+		codes.Aborted, codes.OutOfRange, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// OTLPSeverity returns the OpenTelemetry severity number of a log level.
+func OTLPSeverity(lv Level) logspb.SeverityNumber {
+	switch lv {
+	case LevelDebug:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	case LevelInfo:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	case LevelWarn:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	case LevelError:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	case LevelFatal:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL
+	}
+	return logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+}
+
+func otlpResource(attrs map[string]string) *resourcepb.Resource {
+	if _, ok := attrs["service.name"]; !ok {
+		m := make(map[string]string, len(attrs)+1)
+		for k, v := range attrs {
+			m[k] = v
+		}
+		m["service.name"] = filepath.Base(os.Args[0])
+		attrs = m
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	r := new(resourcepb.Resource)
+	for _, k := range keys {
+		r.Attributes = append(r.Attributes, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: attrs[k]}},
+		})
+	}
+	return r
+}
+
+func otlpRequest(resource *resourcepb.Resource, batch []record) *collogspb.ExportLogsServiceRequest {
+	records := make([]*logspb.LogRecord, 0, len(batch))
+	for _, r := range batch {
+		records = append(records, otlpRecord(r))
+	}
+	return &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: resource,
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: otlpScope},
+				LogRecords: records,
+			}},
+		}},
+	}
+}
+
+// otlpRecord converts an event to a log record: the level becomes the
+// severity, the message the body, the trace and span ids are set on the
+// record and the other fields become attributes.
+func otlpRecord(r record) *logspb.LogRecord {
+	lr := &logspb.LogRecord{
+		TimeUnixNano:         uint64(r.Time),
+		ObservedTimeUnixNano: uint64(r.Time),
+		SeverityNumber:       OTLPSeverity(r.Level),
+		SeverityText:         r.Level.String(),
+	}
+
+	var fields map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(r.Event))
+	d.UseNumber()
+	if err := d.Decode(&fields); err != nil {
+		lr.Body = otlpValue(string(r.Event))
+		return lr
+	}
+
+	if msg, ok := fields[MessageFieldName]; ok {
+		lr.Body = otlpValue(msg)
+	}
+	for _, k := range TraceIDFieldNames {
+		if id := otlpID(fields[k], 16); id != nil {
+			lr.TraceId = id
+			delete(fields, k)
+		}
+	}
+	for _, k := range SpanIDFieldNames {
+		if id := otlpID(fields[k], 8); id != nil {
+			lr.SpanId = id
+			delete(fields, k)
+		}
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		switch k {
+		case MessageFieldName, LevelFieldName, TimestampFieldName:
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		lr.Attributes = append(lr.Attributes, &commonpb.KeyValue{Key: k, Value: otlpValue(fields[k])})
+	}
+	return lr
+}
+
+// otlpID decodes a hex encoded id of n bytes, it returns nil if v is not one.
+func otlpID(v interface{}, n int) []byte {
+	s, ok := v.(string)
+	if !ok || len(s) != 2*n {
+		return nil
+	}
+	id, err := hex.DecodeString(s)
+	if err != nil {
+		return nil
+	}
+	return id
+}
+
+func otlpValue(v interface{}) *commonpb.AnyValue {
+	switch v := v.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: i}}
+		}
+		f, _ := v.Float64()
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: f}}
+	case []interface{}:
+		a := &commonpb.ArrayValue{Values: make([]*commonpb.AnyValue, 0, len(v))}
+		for _, e := range v {
+			a.Values = append(a.Values, otlpValue(e))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: a}}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		kv := &commonpb.KeyValueList{Values: make([]*commonpb.KeyValue, 0, len(v))}
+		for _, k := range keys {
+			kv.Values = append(kv.Values, &commonpb.KeyValue{Key: k, Value: otlpValue(v[k])})
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: kv}}
+	}
+	return &commonpb.AnyValue{}
+}
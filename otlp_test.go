@@ -0,0 +1,142 @@
+package slog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+func Test_otlpRecord(t *testing.T) {
+	lr := otlpRecord(record{
+		Level: LevelWarn,
+		Time:  42,
+		Event: json.RawMessage(`{"level":"warn","ts":"now","msg":"hello","trace_id":"0102030405060708090a0b0c0d0e0f10","span.id":"0102030405060708","n":1,"f":1.5,"ok":true,"tags":["a"],"obj":{"k":"v"}}`),
+	})
+
+	assert.Equal(t, uint64(42), lr.TimeUnixNano)
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, lr.SeverityNumber)
+	assert.Equal(t, "WARN", lr.SeverityText)
+	assert.Equal(t, "hello", lr.Body.GetStringValue())
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, lr.TraceId)
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8}, lr.SpanId)
+
+	require.Len(t, lr.Attributes, 5)
+	assert.Equal(t, "f", lr.Attributes[0].Key)
+	assert.Equal(t, 1.5, lr.Attributes[0].Value.GetDoubleValue())
+	assert.Equal(t, "n", lr.Attributes[1].Key)
+	assert.Equal(t, int64(1), lr.Attributes[1].Value.GetIntValue())
+	assert.Equal(t, "obj", lr.Attributes[2].Key)
+	assert.Equal(t, "v", lr.Attributes[2].Value.GetKvlistValue().Values[0].Value.GetStringValue())
+	assert.Equal(t, "ok", lr.Attributes[3].Key)
+	assert.True(t, lr.Attributes[3].Value.GetBoolValue())
+	assert.Equal(t, "tags", lr.Attributes[4].Key)
+	assert.Equal(t, "a", lr.Attributes[4].Value.GetArrayValue().Values[0].GetStringValue())
+}
+
+func Test_otlpWriter_http(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		req *collogspb.ExportLogsServiceRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		b, _ := io.ReadAll(r.Body)
+		m := new(collogspb.ExportLogsServiceRequest)
+		assert.NoError(t, proto.Unmarshal(b, m))
+		mu.Lock()
+		req = m
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	l := New(&Config{Otlp: &Otlp{Endpoint: srv.URL, Resource: map[string]string{"service.name": "demo"}}})
+	l.Error("boom")
+	require.NoError(t, l.(*Helper).out.release(nil))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotNil(t, req)
+	rl := req.ResourceLogs[0]
+	assert.Equal(t, "service.name", rl.Resource.Attributes[0].Key)
+	assert.Equal(t, "demo", rl.Resource.Attributes[0].Value.GetStringValue())
+	lr := rl.ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, "boom", lr.Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, lr.SeverityNumber)
+}
+
+type logsServer struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu      sync.Mutex
+	records []*logspb.LogRecord
+	token   string
+}
+
+func (s *logsServer) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("x-token")) > 0 {
+		s.token = md.Get("x-token")[0]
+	}
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			s.records = append(s.records, sl.LogRecords...)
+		}
+	}
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func Test_otlpWriter_grpc(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ls := new(logsServer)
+	gs := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(gs, ls)
+	go gs.Serve(ln)
+	defer gs.Stop()
+
+	w, err := newOTLPWriter(&Otlp{
+		Endpoint:      ln.Addr().String(),
+		Protocol:      OTLPProtocolGRPC,
+		Insecure:      true,
+		Gzip:          true,
+		Headers:       map[string]string{"x-token": "secret"},
+		FlushInterval: 10,
+	})
+	require.NoError(t, err)
+	defer w.Close()
+
+	l := newZerolog(w)
+	l.Log(LevelInfo, "hello", "n", 1)
+
+	assert.Eventually(t, func() bool {
+		ls.mu.Lock()
+		defer ls.mu.Unlock()
+		return len(ls.records) == 1
+	}, time.Second, 10*time.Millisecond)
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	assert.Equal(t, "hello", ls.records[0].Body.GetStringValue())
+	assert.Equal(t, "secret", ls.token)
+}
+
+func Test_newOTLPWriter_invalid(t *testing.T) {
+	_, err := newOTLPWriter(&Otlp{})
+	assert.Error(t, err)
+	_, err = newOTLPWriter(&Otlp{Endpoint: "localhost:4317", Protocol: "nope"})
+	assert.Error(t, err)
+}
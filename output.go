@@ -0,0 +1,210 @@
+package slog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	zlog "github.com/rs/zerolog"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// FormatJSON encodes each event as a JSON object.
+	FormatJSON = "json"
+	// FormatConsole encodes each event as a human readable line.
+	FormatConsole = "console"
+	// FormatCBOR encodes each event as a CBOR map, more compact and faster
+	// to parse than JSON. Built with the binary_log tag, zerolog encodes the
+	// events as CBOR natively, the other formats, outputs and the writers
+	// given to SetOutput then get them decoded to JSON. See CBORToJSON to
+	// read the events back.
+	FormatCBOR = "cbor"
+)
+
+// output owns the writers built from a Config, so that they can be reused
+// or closed when the configuration changes at runtime.
+type output struct {
+	sinks map[string]*sink
+	w     io.Writer
+}
+
+// sink is a writer built from a part of a Config.
+type sink struct {
+	// key identifies the configuration the writer was built from.
+	key string
+	w   io.WriteCloser
+}
+
+// newOutput builds the writers described by c. Sinks that are still
+// configured identically in prev are reused instead of being reopened.
+func newOutput(c *Config, prev *output) (*output, error) {
+	o := &output{sinks: make(map[string]*sink)}
+
+	open := func(name, key string, build func() (io.WriteCloser, error)) (io.Writer, error) {
+		if prev != nil {
+			if s, ok := prev.sinks[name]; ok && s.key == key {
+				o.sinks[name] = s
+				return s.w, nil
+			}
+		}
+		w, err := build()
+		if err != nil {
+			return nil, err
+		}
+		o.sinks[name] = &sink{key: key, w: w}
+		return w, nil
+	}
+
+	var ws []io.Writer
+
+	if c.Path != "" {
+		key := fmt.Sprint(c.Path, c.MaxSize, c.MaxAge)
+		w, err := open("file", key, func() (io.WriteCloser, error) {
+			return &lumberjack.Logger{
+				Filename: c.Path,
+				MaxSize:  int(c.MaxSize),
+				MaxAge:   int(c.MaxAge),
+			}, nil
+		})
+		if err != nil {
+			o.release(prev)
+			return nil, err
+		}
+		ws = append(ws, format(c, w))
+	}
+
+	if c.Journald == nil {
+		ws = append(ws, format(c, os.Stdout))
+	} else {
+		w, _ := open("journald", protoKey(c.Journald), func() (io.WriteCloser, error) {
+			return newJournaldWriter(c.Journald), nil
+		})
+		ws = append(ws, jsonEvents(w))
+	}
+
+	if c.Syslog != nil {
+		w, err := open("syslog", protoKey(c.Syslog), func() (io.WriteCloser, error) {
+			return newSyslogWriter(c.Syslog)
+		})
+		if err != nil {
+			o.release(prev)
+			return nil, err
+		}
+		ws = append(ws, jsonEvents(w))
+	}
+
+	if c.Http != nil {
+		w, err := open("http", protoKey(c.Http), func() (io.WriteCloser, error) {
+			return newHTTPWriter(c.Http)
+		})
+		if err != nil {
+			o.release(prev)
+			return nil, err
+		}
+		ws = append(ws, jsonEvents(w))
+	}
+
+	if c.Otlp != nil {
+		w, err := open("otlp", protoKey(c.Otlp), func() (io.WriteCloser, error) {
+			return newOTLPWriter(c.Otlp)
+		})
+		if err != nil {
+			o.release(prev)
+			return nil, err
+		}
+		ws = append(ws, jsonEvents(w))
+	}
+
+	if len(ws) == 1 {
+		o.w = ws[0]
+	} else {
+		o.w = MultiLevelWriter(ws...)
+	}
+	return o, nil
+}
+
+// release closes the sinks of o that are not reused by next.
+func (o *output) release(next *output) error {
+	if o == nil {
+		return nil
+	}
+	var first error
+	for name, s := range o.sinks {
+		if next != nil && next.sinks[name] == s {
+			continue
+		}
+		if err := s.w.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// releaseDelay is how long the replaced sinks stay open, for the events
+// being logged while the configuration changes.
+var releaseDelay = time.Second
+
+// releaseLater closes the sinks of o that are not reused by next after
+// releaseDelay, reporting the errors.
+func (o *output) releaseLater(next *output) {
+	if o == nil {
+		return
+	}
+	time.AfterFunc(releaseDelay, func() {
+		if err := o.release(next); err != nil {
+			reportError(err)
+		}
+	})
+}
+
+// format wraps w with the encoding selected by c.Format.
+func format(c *Config, w io.Writer) io.Writer {
+	switch {
+	case strings.EqualFold(c.Format, FormatConsole):
+		return zlog.ConsoleWriter{Out: w, NoColor: true, TimeFormat: TimeFieldFormat}
+	case strings.EqualFold(c.Format, FormatCBOR):
+		return cborWriter{w: w}
+	}
+	return jsonEvents(w)
+}
+
+// jsonEvents wraps w to receive the events as JSON when zerolog encodes them
+// as CBOR, see binaryLog.
+func jsonEvents(w io.Writer) io.Writer {
+	if !binaryLog {
+		return w
+	}
+	return jsonWriter{w: w}
+}
+
+// jsonWriter decodes the CBOR events written to it to JSON lines.
+type jsonWriter struct {
+	w io.Writer
+}
+
+func (j jsonWriter) Write(p []byte) (int, error) {
+	if _, err := j.w.Write(eventJSON(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (j jsonWriter) WriteLevel(l zlog.Level, p []byte) (int, error) {
+	lw, ok := j.w.(zlog.LevelWriter)
+	if !ok {
+		return j.Write(p)
+	}
+	if _, err := lw.WriteLevel(l, eventJSON(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func protoKey(m proto.Message) string {
+	b, _ := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	return string(b)
+}
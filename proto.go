@@ -0,0 +1,314 @@
+package slog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// redactedValue replaces the redacted string fields of logged messages.
+const redactedValue = "[REDACTED]"
+
+// debugRedactField is the number of the debug_redact field option, unknown
+// to older versions of descriptorpb.
+const debugRedactField = 16
+
+// ProtoMarshalOptions are the options encoding the protobuf messages logged
+// as field values. The grpc payloads logged by the interceptors keep the
+// default protojson encoding, with JSON names and without unpopulated
+// fields.
+var ProtoMarshalOptions = protojson.MarshalOptions{
+	UseProtoNames:   true,
+	EmitUnpopulated: true,
+}
+
+// payloadMarshalOptions are the options encoding the grpc payloads.
+var payloadMarshalOptions = protojson.MarshalOptions{}
+
+// ProtoMask returns a field value logging only the fields of m in the paths
+// of mask, e.g.
+//
+//	logger.Info("user", slog.ProtoMask(u, &fieldmaskpb.FieldMask{Paths: []string{"name", "address.city"}}))
+//
+// The paths going through a repeated or map field apply to each of its
+// elements, e.g. "items.name" keeps the name of each item. Paths naming no
+// field of m are ignored.
+func ProtoMask(m proto.Message, mask *fieldmaskpb.FieldMask) json.Marshaler {
+	return maskedMessage{m: m, mask: newMaskTree(mask.GetPaths())}
+}
+
+type maskedMessage struct {
+	m    proto.Message
+	mask maskTree
+}
+
+func (m maskedMessage) MarshalJSON() ([]byte, error) {
+	return marshalProto(ProtoMarshalOptions, m.m, m.mask, nil), nil
+}
+
+// maskTree holds the paths of a field mask by field name, a nil subtree
+// keeping the whole field.
+type maskTree map[string]maskTree
+
+func newMaskTree(paths []string) maskTree {
+	t := maskTree{}
+	for _, p := range paths {
+		n := t
+		names := strings.Split(p, ".")
+		for i, name := range names {
+			c, ok := n[name]
+			if ok && c == nil {
+				break
+			}
+			if i == len(names)-1 {
+				n[name] = nil
+				break
+			}
+			if !ok {
+				c = maskTree{}
+				n[name] = c
+			}
+			n = c
+		}
+	}
+	return t
+}
+
+// renderMessage renders a grpc payload as JSON, with the fields in redact
+// masked.
+func renderMessage(m interface{}, redact map[string]struct{}) json.RawMessage {
+	pm, ok := m.(proto.Message)
+	if !ok {
+		b, _ := json.Marshal(m)
+		return b
+	}
+	return marshalProto(payloadMarshalOptions, pm, nil, redact)
+}
+
+// marshalProto encodes m with opts, keeping only the fields in mask if not
+// nil. The fields named in redact and the fields with the debug_redact
+// option are replaced with redactedValue. Both apply to the JSON, as
+// unpopulated fields may be emitted, and keep the order of the fields.
+func marshalProto(opts protojson.MarshalOptions, m proto.Message, mask maskTree, redact map[string]struct{}) json.RawMessage {
+	b, err := opts.Marshal(m)
+	if err != nil {
+		b, _ = json.Marshal(err.Error())
+		return b
+	}
+	md := m.ProtoReflect().Descriptor()
+	if mask != nil {
+		b = maskJSON(b, md, mask)
+	}
+	if len(redact) > 0 || hasDebugRedact(md) {
+		b = redactJSON(b, md, redact)
+	}
+	return b
+}
+
+// maskJSON keeps the fields of v, the JSON encoding of a message of type
+// md, in mask.
+func maskJSON(v json.RawMessage, md protoreflect.MessageDescriptor, mask maskTree) json.RawMessage {
+	return editMessageJSON(v, md, func(fd protoreflect.FieldDescriptor, v json.RawMessage) (json.RawMessage, bool) {
+		sub, ok := mask[string(fd.Name())]
+		if !ok || sub == nil {
+			return v, ok
+		}
+		return editFieldJSON(v, fd, func(md protoreflect.MessageDescriptor, v json.RawMessage) json.RawMessage {
+			return maskJSON(v, md, sub)
+		}), true
+	})
+}
+
+// redactJSON replaces the fields of v, the JSON encoding of a message of
+// type md, named in redact or with the debug_redact option, recursively.
+func redactJSON(v json.RawMessage, md protoreflect.MessageDescriptor, redact map[string]struct{}) json.RawMessage {
+	return editMessageJSON(v, md, func(fd protoreflect.FieldDescriptor, v json.RawMessage) (json.RawMessage, bool) {
+		if _, ok := redact[string(fd.Name())]; ok || isDebugRedact(fd) {
+			return redactedJSON, true
+		}
+		return editFieldJSON(v, fd, func(md protoreflect.MessageDescriptor, v json.RawMessage) json.RawMessage {
+			if len(redact) == 0 && !hasDebugRedact(md) {
+				return v
+			}
+			return redactJSON(v, md, redact)
+		}), true
+	})
+}
+
+var redactedJSON = json.RawMessage(`"` + redactedValue + `"`)
+
+// editMessageJSON rewrites v, the JSON encoding of a message of type md,
+// with the values returned by edit for its fields. The fields edit returns
+// false for are removed, the keys that are not fields of md are kept as is.
+func editMessageJSON(v json.RawMessage, md protoreflect.MessageDescriptor, edit func(fd protoreflect.FieldDescriptor, v json.RawMessage) (json.RawMessage, bool)) json.RawMessage {
+	return editJSONObject(v, func(key string, v json.RawMessage) (json.RawMessage, bool) {
+		if fd := jsonField(md, key); fd != nil {
+			return edit(fd, v)
+		}
+		return v, true
+	})
+}
+
+// editFieldJSON rewrites the messages of v, the JSON encoding of the field
+// fd, with edit: the message itself, or each of the elements of a repeated
+// or map field.
+func editFieldJSON(v json.RawMessage, fd protoreflect.FieldDescriptor, edit func(md protoreflect.MessageDescriptor, v json.RawMessage) json.RawMessage) json.RawMessage {
+	switch {
+	case fd.IsMap():
+		if md := fd.MapValue().Message(); md != nil {
+			return editJSONObject(v, func(_ string, v json.RawMessage) (json.RawMessage, bool) {
+				return edit(md, v), true
+			})
+		}
+	case fd.IsList():
+		if md := fd.Message(); md != nil {
+			return editJSONArray(v, func(v json.RawMessage) json.RawMessage {
+				return edit(md, v)
+			})
+		}
+	case fd.Message() != nil:
+		return edit(fd.Message(), v)
+	}
+	return v
+}
+
+// editJSONObject rewrites the JSON object v with the values returned by
+// edit, keeping the order of the keys. The keys edit returns false for are
+// removed. v is returned unchanged if it is not an object, as the
+// well-known types may be encoded.
+func editJSONObject(v json.RawMessage, edit func(key string, v json.RawMessage) (json.RawMessage, bool)) json.RawMessage {
+	d := json.NewDecoder(bytes.NewReader(v))
+	if t, err := d.Token(); err != nil || t != json.Delim('{') {
+		return v
+	}
+	out := []byte{'{'}
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return v
+		}
+		key, _ := t.(string)
+		var val json.RawMessage
+		if err := d.Decode(&val); err != nil {
+			return v
+		}
+		if val, ok := edit(key, val); ok {
+			if len(out) > 1 {
+				out = append(out, ',')
+			}
+			out = appendJSONString(out, []byte(key))
+			out = append(out, ':')
+			out = append(out, val...)
+		}
+	}
+	return append(out, '}')
+}
+
+// editJSONArray rewrites the elements of the JSON array v with edit, v is
+// returned unchanged if it is not an array.
+func editJSONArray(v json.RawMessage, edit func(v json.RawMessage) json.RawMessage) json.RawMessage {
+	d := json.NewDecoder(bytes.NewReader(v))
+	if t, err := d.Token(); err != nil || t != json.Delim('[') {
+		return v
+	}
+	out := []byte{'['}
+	for d.More() {
+		var val json.RawMessage
+		if err := d.Decode(&val); err != nil {
+			return v
+		}
+		if len(out) > 1 {
+			out = append(out, ',')
+		}
+		out = append(out, edit(val)...)
+	}
+	return append(out, ']')
+}
+
+// jsonField returns the field of md encoded with key, by JSON name or by
+// proto name depending on the marshal options.
+func jsonField(md protoreflect.MessageDescriptor, key string) protoreflect.FieldDescriptor {
+	if fd := md.Fields().ByJSONName(key); fd != nil {
+		return fd
+	}
+	return md.Fields().ByName(protoreflect.Name(key))
+}
+
+// debugRedactTypes caches whether the messages of a type have fields with
+// the debug_redact option, directly or in nested messages.
+var debugRedactTypes sync.Map
+
+func hasDebugRedact(md protoreflect.MessageDescriptor) bool {
+	if v, ok := debugRedactTypes.Load(md.FullName()); ok {
+		return v.(bool)
+	}
+	has := findDebugRedact(md, make(map[protoreflect.FullName]struct{}))
+	debugRedactTypes.Store(md.FullName(), has)
+	return has
+}
+
+func findDebugRedact(md protoreflect.MessageDescriptor, seen map[protoreflect.FullName]struct{}) bool {
+	if _, ok := seen[md.FullName()]; ok {
+		return false
+	}
+	seen[md.FullName()] = struct{}{}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if isDebugRedact(fd) {
+			return true
+		}
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+		if fd.Message() != nil && findDebugRedact(fd.Message(), seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDebugRedact reports whether fd has the debug_redact option, read from
+// the unknown fields of its options when descriptorpb does not know it.
+func isDebugRedact(fd protoreflect.FieldDescriptor) bool {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	if !ok || opts == nil {
+		return false
+	}
+	m := opts.ProtoReflect()
+	if f := m.Descriptor().Fields().ByNumber(debugRedactField); f != nil {
+		return m.Get(f).Bool()
+	}
+
+	redact := false
+	b := m.GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return false
+		}
+		b = b[n:]
+		if num == debugRedactField && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return false
+			}
+			redact = v != 0
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return false
+		}
+		b = b[n:]
+	}
+	return redact
+}
@@ -0,0 +1,148 @@
+package slog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// newTestUser builds a message of a type declared as:
+//
+//	message Address { string city = 1; string street = 2; }
+//	message User {
+//	  string name = 1;
+//	  string password = 2 [debug_redact = true];
+//	  Address address = 3;
+//	  int32 age = 4;
+//	  int32 pin = 5 [debug_redact = true];
+//	  repeated Address past_addresses = 6;
+//	}
+func newTestUser(t *testing.T) protoreflect.Message {
+	redact := &descriptorpb.FieldOptions{}
+	redact.ProtoReflect().SetUnknown(protowire.AppendVarint(
+		protowire.AppendTag(nil, debugRedactField, protowire.VarintType), 1))
+
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+	}
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING
+	password := field("password", 2, str)
+	password.Options = redact
+	address := field("address", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	address.TypeName = proto.String(".slogtest.Address")
+	pin := field("pin", 5, descriptorpb.FieldDescriptorProto_TYPE_INT32)
+	pin.Options = redact
+	past := field("past_addresses", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	past.JsonName = proto.String("pastAddresses")
+	past.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	past.TypeName = address.TypeName
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("slogtest/user.proto"),
+		Package: proto.String("slogtest"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Address"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("city", 1, str), field("street", 2, str),
+			}},
+			{Name: proto.String("User"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, str), password, address,
+				field("age", 4, descriptorpb.FieldDescriptorProto_TYPE_INT32), pin, past,
+			}},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	user := dynamicpb.NewMessage(fd.Messages().ByName("User"))
+	newAddr := func(city string) protoreflect.Value {
+		addr := dynamicpb.NewMessage(fd.Messages().ByName("Address"))
+		addr.Set(addr.Descriptor().Fields().ByName("city"), protoreflect.ValueOfString(city))
+		return protoreflect.ValueOfMessage(addr)
+	}
+	fields := user.Descriptor().Fields()
+	user.Set(fields.ByName("name"), protoreflect.ValueOfString("alice"))
+	user.Set(fields.ByName("password"), protoreflect.ValueOfString("secret"))
+	user.Set(fields.ByName("address"), newAddr("Paris"))
+	pastAddrs := user.Mutable(fields.ByName("past_addresses")).List()
+	pastAddrs.Append(newAddr("Lyon"))
+	pastAddrs.Append(newAddr("Nice"))
+	return user
+}
+
+func TestProto(t *testing.T) {
+	user := newTestUser(t).Interface()
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{
+			name:  "message",
+			value: user,
+			want:  `{"name":"alice","password":"[REDACTED]","address":{"city":"Paris","street":""},"age":0,"pin":"[REDACTED]","past_addresses":[{"city":"Lyon","street":""},{"city":"Nice","street":""}]}`,
+		},
+		{
+			name:  "mask",
+			value: ProtoMask(user, &fieldmaskpb.FieldMask{Paths: []string{"name", "address.city", "unknown"}}),
+			want:  `{"name":"alice","address":{"city":"Paris"}}`,
+		},
+		{
+			name:  "mask redacted",
+			value: ProtoMask(user, &fieldmaskpb.FieldMask{Paths: []string{"password", "address", "address.city"}}),
+			want:  `{"password":"[REDACTED]","address":{"city":"Paris","street":""}}`,
+		},
+		{
+			name:  "mask repeated",
+			value: ProtoMask(user, &fieldmaskpb.FieldMask{Paths: []string{"past_addresses.city"}}),
+			want:  `{"past_addresses":[{"city":"Lyon"},{"city":"Nice"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			newZerolog(buf).Log(LevelInfo, "user", tt.value)
+			assert.JSONEq(t, `{"level":"info","user":`+tt.want+`}`, buf.String())
+			// protojson adds random spaces.
+			compact := new(bytes.Buffer)
+			require.NoError(t, json.Compact(compact, buf.Bytes()))
+			assert.Contains(t, compact.String(), tt.want, "in the order of the fields")
+		})
+	}
+
+	// the logged message is left untouched.
+	assert.Equal(t, "secret", user.ProtoReflect().Get(user.ProtoReflect().Descriptor().Fields().ByName("password")).String())
+}
+
+func TestProtoMarshalOptions(t *testing.T) {
+	defer func(o protojson.MarshalOptions) { ProtoMarshalOptions = o }(ProtoMarshalOptions)
+	ProtoMarshalOptions.UseProtoNames = false
+	ProtoMarshalOptions.EmitUnpopulated = false
+
+	buf := new(bytes.Buffer)
+	newZerolog(buf).Log(LevelInfo, "config", &Config{MaxSize: 10})
+	assert.JSONEq(t, `{"level":"info","config":{"maxSize":10}}`, buf.String())
+}
+
+func TestRenderMessage(t *testing.T) {
+	// the grpc payloads keep the default protojson encoding.
+	b := renderMessage(newTestUser(t).Interface(), map[string]struct{}{"name": {}})
+	assert.JSONEq(t, `{"name":"[REDACTED]","password":"[REDACTED]","address":{"city":"Paris"},"pastAddresses":[{"city":"Lyon"},{"city":"Nice"}]}`, string(b))
+}
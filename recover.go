@@ -0,0 +1,187 @@
+package slog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecoverOption configures the panic recovery helpers.
+type RecoverOption func(*recoverOptions)
+
+type recoverOptions struct {
+	level   Level
+	repanic bool
+}
+
+// WithPanicLevel sets the level of the recovered panics. default is
+// LevelError, LevelFatal exits the program once logged.
+func WithPanicLevel(lv Level) RecoverOption {
+	return func(o *recoverOptions) {
+		o.level = lv
+	}
+}
+
+// WithRepanic panics again with the recovered value once it is logged.
+func WithRepanic() RecoverOption {
+	return func(o *recoverOptions) {
+		o.repanic = true
+	}
+}
+
+func newRecoverOptions(opts []RecoverOption) *recoverOptions {
+	o := &recoverOptions{level: LevelError}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Recover logs the panicking value with the stack of the panic. It must be
+// deferred directly:
+//
+//	go func() {
+//		defer slog.Recover(logger)
+//		...
+//	}()
+func Recover(logger FullLogger, opts ...RecoverOption) {
+	if v := recover(); v != nil {
+		logPanic(logger, newRecoverOptions(opts), v)
+	}
+}
+
+// RecoverCtx is like Recover with the logger of ctx, see FromContext.
+func RecoverCtx(ctx context.Context, opts ...RecoverOption) {
+	if v := recover(); v != nil {
+		logPanic(FromContext(ctx), newRecoverOptions(opts), v)
+	}
+}
+
+// RecoverHandler wraps h to log its panics and reply with an internal
+// server error, unless the handler already wrote the response headers.
+func RecoverHandler(logger FullLogger, h http.Handler, opts ...RecoverOption) http.Handler {
+	o := newRecoverOptions(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			logPanic(logger, o, v)
+			if rw.status == 0 {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+		h.ServeHTTP(rw, r)
+	})
+}
+
+// UnaryServerRecoveryInterceptor returns a grpc interceptor logging the
+// panics of unary handlers, the call fails with codes.Internal.
+func UnaryServerRecoveryInterceptor(logger FullLogger, opts ...RecoverOption) grpc.UnaryServerInterceptor {
+	o := newRecoverOptions(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (reply interface{}, err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				logPanic(logger, o, v)
+				err = status.Errorf(codes.Internal, "panic: %v", v)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerRecoveryInterceptor returns a grpc interceptor logging the
+// panics of stream handlers, the stream fails with codes.Internal.
+func StreamServerRecoveryInterceptor(logger FullLogger, opts ...RecoverOption) grpc.StreamServerInterceptor {
+	o := newRecoverOptions(opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				logPanic(logger, o, v)
+				err = status.Errorf(codes.Internal, "panic: %v", v)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func logPanic(logger FullLogger, o *recoverOptions, v interface{}) {
+	var kvs []interface{}
+	if err, ok := v.(error); ok {
+		// leading, an error is never taken for a value.
+		kvs = append(kvs, err)
+	}
+	kvs = append(kvs,
+		"panic recovered",
+		"panic", fmt.Sprint(v),
+		ErrorStackFieldName, panicStack(),
+	)
+	logger.Log(o.level, kvs...)
+	if o.repanic {
+		panic(v)
+	}
+}
+
+// panicStack returns the frames of the panicking goroutine, from the
+// function that panicked, in the format of ErrorStackMarshaler.
+func panicStack() []map[string]string {
+	pcs := make([]uintptr, 64)
+	for {
+		n := runtime.Callers(1, pcs)
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, 2*len(pcs))
+	}
+
+	var (
+		stack    []map[string]string
+		panicked bool
+	)
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		switch {
+		case f.Function == "runtime.gopanic":
+			panicked = true
+			stack = stack[:0]
+		case panicked && strings.HasPrefix(f.Function, "runtime.") && len(stack) == 0:
+			// runtime.panicmem, runtime.sigpanic and the like.
+		default:
+			stack = append(stack, stackFrame(f))
+		}
+		if !more {
+			return stack
+		}
+	}
+}
+
+// shortFuncName strips the package path of a function name.
+func shortFuncName(name string) string {
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+func shortFileName(file string) string {
+	if i := strings.LastIndexByte(file, '/'); i >= 0 {
+		return file[i+1:]
+	}
+	return file
+}
@@ -0,0 +1,154 @@
+package slog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func panicking() {
+	var m map[string]int
+	m["boom"] = 1
+}
+
+func TestRecover(t *testing.T) {
+	buf := new(syncBuffer)
+	logger := &Helper{log: newZerolog(buf)}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer Recover(logger)
+		panicking()
+	}()
+	<-done
+
+	events := buf.events(t)
+	require.Len(t, events, 1)
+	e := events[0]
+	assert.Equal(t, "error", e["level"])
+	assert.Equal(t, "panic recovered", e["msg"])
+	assert.Contains(t, e["panic"], "assignment to entry in nil map")
+	assert.Contains(t, e[ErrorFieldName], "assignment to entry in nil map")
+
+	stack, ok := e[ErrorStackFieldName].([]interface{})
+	require.True(t, ok)
+	require.NotEmpty(t, stack)
+	top := stack[0].(map[string]interface{})
+	assert.Equal(t, "panicking", top["func"])
+	assert.Equal(t, "recover_test.go", top["source"])
+}
+
+func deepPanic(n int) {
+	if n == 0 {
+		panicking()
+	}
+	deepPanic(n - 1)
+}
+
+func TestRecover_deepStack(t *testing.T) {
+	buf := new(syncBuffer)
+	logger := &Helper{log: newZerolog(buf)}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer Recover(logger)
+		deepPanic(100)
+	}()
+	<-done
+
+	events := buf.events(t)
+	require.Len(t, events, 1)
+	stack := events[0][ErrorStackFieldName].([]interface{})
+	assert.Greater(t, len(stack), 100, "the whole stack is kept")
+	assert.Equal(t, "panicking", stack[0].(map[string]interface{})["func"])
+}
+
+func TestRecoverCtx(t *testing.T) {
+	buf := new(syncBuffer)
+	ctx := WithLogger(context.Background(), &Helper{log: newZerolog(buf)})
+
+	func() {
+		defer RecoverCtx(ctx, WithPanicLevel(LevelWarn))
+		panic("oops")
+	}()
+
+	events := buf.events(t)
+	require.Len(t, events, 1)
+	assert.Equal(t, "warn", events[0]["level"])
+	assert.Equal(t, "oops", events[0]["panic"])
+}
+
+func TestRecover_repanic(t *testing.T) {
+	buf := new(syncBuffer)
+	logger := &Helper{log: newZerolog(buf)}
+
+	err := errors.New("fatal")
+	assert.PanicsWithValue(t, err, func() {
+		defer Recover(logger, WithRepanic())
+		panic(err)
+	})
+	assert.Len(t, buf.events(t), 1)
+}
+
+func TestRecoverHandler(t *testing.T) {
+	buf := new(syncBuffer)
+	logger := &Helper{log: newZerolog(buf)}
+
+	h := RecoverHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler")
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Len(t, buf.events(t), 1)
+
+	written := RecoverHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("after the headers")
+	}))
+	w = httptest.NewRecorder()
+	written.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Len(t, buf.events(t), 2)
+
+	abort := RecoverHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	assert.Panics(t, func() {
+		abort.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestServerRecoveryInterceptors(t *testing.T) {
+	buf := new(syncBuffer)
+	logger := &Helper{log: newZerolog(buf)}
+
+	unary := UnaryServerRecoveryInterceptor(logger)
+	_, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("unary")
+	})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	stream := StreamServerRecoveryInterceptor(logger)
+	err = stream(nil, nil, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+		panic("stream")
+	})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	events := buf.events(t)
+	require.Len(t, events, 2)
+	b, _ := json.Marshal(events)
+	assert.Contains(t, string(b), `"panic":"unary"`)
+	assert.Contains(t, string(b), `"panic":"stream"`)
+}
@@ -0,0 +1,252 @@
+package slog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	zlog "github.com/rs/zerolog"
+)
+
+const (
+	// SyslogRFC5424 is the syslog protocol described in RFC 5424.
+	SyslogRFC5424 = "rfc5424"
+	// SyslogRFC3164 is the BSD syslog protocol described in RFC 3164.
+	SyslogRFC3164 = "rfc3164"
+)
+
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// syslogSockets are the local syslog sockets tried when no address is set.
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogTimeout bounds connecting to the syslog server and writing a
+// message, the event is dropped when it expires.
+var syslogTimeout = 5 * time.Second
+
+// SyslogSeverity returns the syslog severity of a log level.
+func SyslogSeverity(lv Level) int {
+	switch lv {
+	case LevelDebug:
+		return 7
+	case LevelInfo:
+		return 6
+	case LevelWarn:
+		return 4
+	case LevelError:
+		return 3
+	case LevelFatal:
+		return 2
+	}
+	return 5
+}
+
+var (
+	_ zlog.LevelWriter = (*syslogWriter)(nil)
+	_ io.Closer        = (*syslogWriter)(nil)
+)
+
+// syslogWriter sends each event as a syslog message. The connection is
+// established on the first write and re-established after write errors.
+type syslogWriter struct {
+	c         *Syslog
+	facility  int
+	app       string
+	hostname  string
+	pid       string
+	tlsConfig *tls.Config
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newSyslogWriter(c *Syslog) (*syslogWriter, error) {
+	w := &syslogWriter{
+		c:   c,
+		app: c.AppName,
+		pid: strconv.Itoa(os.Getpid()),
+	}
+
+	facility := strings.ToLower(c.Facility)
+	if facility == "" {
+		facility = "user"
+	}
+	f, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("syslog: unknown facility %q", c.Facility)
+	}
+	w.facility = f
+
+	switch strings.ToLower(c.Format) {
+	case "", SyslogRFC5424, SyslogRFC3164:
+	default:
+		return nil, fmt.Errorf("syslog: unknown format %q", c.Format)
+	}
+
+	if w.app == "" {
+		w.app = filepath.Base(os.Args[0])
+	}
+	w.hostname, _ = os.Hostname()
+	if w.hostname == "" {
+		w.hostname = "-"
+	}
+
+	if c.Tls {
+		w.tlsConfig = &tls.Config{}
+		if c.CaFile != "" {
+			pem, err := os.ReadFile(c.CaFile)
+			if err != nil {
+				return nil, fmt.Errorf("syslog: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("syslog: no certificates in %s", c.CaFile)
+			}
+			w.tlsConfig.RootCAs = pool
+		}
+	}
+	return w, nil
+}
+
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zlog.NoLevel, p)
+}
+
+func (w *syslogWriter) WriteLevel(l zlog.Level, p []byte) (int, error) {
+	msg := w.format(levelOf(l), bytes.TrimRight(p, "\n"))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// retry once on a fresh connection, the server may have restarted.
+	var err error
+	for i := 0; i < 2; i++ {
+		if w.conn == nil {
+			if w.conn, err = w.dial(); err != nil {
+				return 0, err
+			}
+		}
+		if err = w.conn.SetWriteDeadline(time.Now().Add(syslogTimeout)); err == nil {
+			if _, err = w.conn.Write(frame(w.conn, msg)); err == nil {
+				return len(p), nil
+			}
+		}
+		// the message may be partially written, the connection is unusable.
+		w.conn.Close()
+		w.conn = nil
+		if isTimeout(err) {
+			// the server is stuck rather than gone, drop the event.
+			break
+		}
+	}
+	return 0, err
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *syslogWriter) dial() (net.Conn, error) {
+	network, addr := strings.ToLower(w.c.Network), w.c.Address
+	d := &net.Dialer{Timeout: syslogTimeout}
+	switch network {
+	case "", "unix", "unixgram":
+		return w.dialLocal(d, network, addr)
+	case "tcp", "tcp4", "tcp6":
+		if w.tlsConfig != nil {
+			return tls.DialWithDialer(d, network, addr, w.tlsConfig)
+		}
+	case "udp", "udp4", "udp6":
+	default:
+		return nil, fmt.Errorf("syslog: unknown network %q", w.c.Network)
+	}
+	return d.Dial(network, addr)
+}
+
+func (w *syslogWriter) dialLocal(d *net.Dialer, network, addr string) (net.Conn, error) {
+	networks := []string{"unixgram", "unix"}
+	if network != "" {
+		networks = []string{network}
+	}
+	addrs := syslogSockets
+	if addr != "" {
+		addrs = []string{addr}
+	}
+	for _, n := range networks {
+		for _, a := range addrs {
+			if conn, err := d.Dial(n, a); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, errors.New("syslog: no local syslog socket found")
+}
+
+// frame delimits msg for stream connections, datagrams are sent as is.
+func frame(conn net.Conn, msg []byte) []byte {
+	switch conn.RemoteAddr().Network() {
+	case "tcp":
+		// RFC 6587 octet counting.
+		return append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	case "unix":
+		return append(msg, '\n')
+	}
+	return msg
+}
+
+func (w *syslogWriter) format(lv Level, msg []byte) []byte {
+	pri := w.facility*8 + SyslogSeverity(lv)
+	now := TimestampFunc()
+
+	var b bytes.Buffer
+	if strings.EqualFold(w.c.Format, SyslogRFC3164) {
+		fmt.Fprintf(&b, "<%d>%s %s %s[%s]: ", pri, now.Format(time.Stamp), w.hostname, w.app, w.pid)
+	} else {
+		fmt.Fprintf(&b, "<%d>1 %s %s %s %s - - ", pri, now.Format("2006-01-02T15:04:05.000000Z07:00"), w.hostname, w.app, w.pid)
+	}
+	b.Write(msg)
+	return b.Bytes()
+}
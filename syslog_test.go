@@ -0,0 +1,200 @@
+package slog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	zlog "github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslogSeverity(t *testing.T) {
+	tests := []struct {
+		lv   Level
+		want int
+	}{
+		{LevelDebug, 7},
+		{LevelInfo, 6},
+		{LevelWarn, 4},
+		{LevelError, 3},
+		{LevelFatal, 2},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, SyslogSeverity(tt.lv), tt.lv.String())
+	}
+}
+
+func Test_syslogWriter_format(t *testing.T) {
+	TimestampFunc = func() time.Time {
+		return time.Date(2001, time.February, 3, 4, 5, 6, 7000, time.UTC)
+	}
+	defer func() { TimestampFunc = time.Now }()
+
+	tests := []struct {
+		name string
+		c    *Syslog
+		lv   Level
+		want string
+	}{
+		{
+			name: "rfc5424",
+			c:    &Syslog{Facility: "local0", AppName: "app"},
+			lv:   LevelError,
+			want: "<131>1 2001-02-03T04:05:06.000007Z host app 42 - - {}",
+		},
+		{
+			name: "rfc3164",
+			c:    &Syslog{Format: SyslogRFC3164, AppName: "app"},
+			lv:   LevelWarn,
+			want: "<12>Feb  3 04:05:06 host app[42]: {}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := newSyslogWriter(tt.c)
+			require.NoError(t, err)
+			w.hostname, w.pid = "host", "42"
+			assert.Equal(t, tt.want, string(w.format(tt.lv, []byte("{}"))))
+		})
+	}
+}
+
+func Test_newSyslogWriter_invalid(t *testing.T) {
+	_, err := newSyslogWriter(&Syslog{Facility: "nope"})
+	assert.Error(t, err)
+	_, err = newSyslogWriter(&Syslog{Format: "nope"})
+	assert.Error(t, err)
+	_, err = newSyslogWriter(&Syslog{Tls: true, CaFile: "/does/not/exist"})
+	assert.Error(t, err)
+}
+
+func Test_syslogWriter_udp(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	l := New(&Config{Level: "debug", Syslog: &Syslog{Network: "udp", Address: pc.LocalAddr().String()}})
+	l.Warn("hello")
+
+	buf := make([]byte, 1024)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(buf[:n]), "<12>1 "), string(buf[:n]))
+	assert.Contains(t, string(buf[:n]), `"msg":"hello"`)
+}
+
+func Test_syslogWriter_unixgram(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "log.sock")
+	pc, err := net.ListenPacket("unixgram", addr)
+	require.NoError(t, err)
+	defer pc.Close()
+
+	w, err := newSyslogWriter(&Syslog{Address: addr})
+	require.NoError(t, err)
+	defer w.Close()
+	_, err = w.WriteLevel(zlog.InfoLevel, []byte("{}\n"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(buf[:n]), " {}"), string(buf[:n]))
+}
+
+func Test_syslogWriter_tcpReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	w, err := newSyslogWriter(&Syslog{Network: "tcp", Address: ln.Addr().String()})
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.WriteLevel(zlog.InfoLevel, []byte("first"))
+	require.NoError(t, err)
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	assert.Contains(t, readFrame(t, conn), "first")
+	conn.Close()
+
+	// the peer is gone, writes must eventually land on a new connection.
+	assert.Eventually(t, func() bool {
+		_, err := w.WriteLevel(zlog.InfoLevel, []byte("second"))
+		return err == nil && w.conn.LocalAddr().String() != conn.RemoteAddr().String()
+	}, time.Second, 10*time.Millisecond)
+	conn, err = ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Contains(t, readFrame(t, conn), "second")
+}
+
+func Test_syslogWriter_writeTimeout(t *testing.T) {
+	defer func(d time.Duration) { syslogTimeout = d }(syslogTimeout)
+	syslogTimeout = 50 * time.Millisecond
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	w, err := newSyslogWriter(&Syslog{Network: "tcp", Address: ln.Addr().String()})
+	require.NoError(t, err)
+	defer w.Close()
+
+	// the server accepts but never reads, the socket buffers fill up.
+	big := []byte(strings.Repeat("x", 64<<20))
+	start := time.Now()
+	_, err = w.WriteLevel(zlog.InfoLevel, big)
+	assert.True(t, isTimeout(err), "%v", err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Nil(t, w.conn, "reconnects on the next write")
+}
+
+func Test_syslogWriter_tls(t *testing.T) {
+	srv := httptest.NewUnstartedServer(nil)
+	srv.StartTLS()
+	defer srv.Close()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", srv.TLS)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o644))
+
+	w, err := newSyslogWriter(&Syslog{Network: "tcp", Address: ln.Addr().String(), Tls: true, CaFile: ca})
+	require.NoError(t, err)
+	defer w.Close()
+
+	go w.WriteLevel(zlog.InfoLevel, []byte("secure"))
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Contains(t, readFrame(t, conn), "secure")
+}
+
+// readFrame reads one octet counted message.
+func readFrame(t *testing.T, conn net.Conn) string {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	r := bufio.NewReader(conn)
+	size, err := r.ReadString(' ')
+	require.NoError(t, err)
+	n, err := strconv.Atoi(strings.TrimSpace(size))
+	require.NoError(t, err)
+	buf := make([]byte, n)
+	_, err = io.ReadFull(r, buf)
+	require.NoError(t, err)
+	return string(buf)
+}
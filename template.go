@@ -0,0 +1,153 @@
+package slog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	zlog "github.com/rs/zerolog"
+)
+
+// maxTemplates bounds the number of cached templates, so that templates
+// built at runtime do not grow the cache without limit. Templates beyond it
+// are parsed on each call.
+const maxTemplates = 1024
+
+// templates caches the parsed message templates by text, templateCount
+// counts them.
+var (
+	templates     sync.Map
+	templateCount int32
+)
+
+// messageTemplate is a parsed message template, text[i] comes before the
+// placeholder names[i].
+type messageTemplate struct {
+	text  []string
+	names []string
+}
+
+// parseTemplate parses the {name} placeholders of tmpl, "{{" and "}}" are
+// literal braces. Braces around anything else than a name are kept as is.
+func parseTemplate(tmpl string) *messageTemplate {
+	if t, ok := templates.Load(tmpl); ok {
+		return t.(*messageTemplate)
+	}
+
+	t := new(messageTemplate)
+	var b strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		c := tmpl[i]
+		switch {
+		case (c == '{' || c == '}') && i+1 < len(tmpl) && tmpl[i+1] == c:
+			b.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(tmpl[i+1:], '}')
+			if end <= 0 || !isPlaceholderName(tmpl[i+1:i+1+end]) {
+				b.WriteByte(c)
+				continue
+			}
+			t.text = append(t.text, b.String())
+			t.names = append(t.names, tmpl[i+1:i+1+end])
+			b.Reset()
+			i += end + 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	t.text = append(t.text, b.String())
+
+	if atomic.AddInt32(&templateCount, 1) <= maxTemplates {
+		if _, loaded := templates.LoadOrStore(tmpl, t); loaded {
+			atomic.AddInt32(&templateCount, -1)
+		}
+	} else {
+		atomic.AddInt32(&templateCount, -1)
+	}
+	return t
+}
+
+func isPlaceholderName(s string) bool {
+	for _, c := range s {
+		if !(c == '_' || c == '.' || c == '-' ||
+			'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// write adds to e the rendered message, the template and one field per
+// placeholder, args are bound to the placeholders in order. The fields of
+// placeholders named after an event field or after an earlier placeholder
+// get a "_<n>" suffix. The placeholders without argument are rendered as is. The arguments without placeholder are
+// added as key/value pairs, errors among them are logged as by Log.
+func (t *messageTemplate) write(e *zlog.Event, tmpl string, args []interface{}) {
+	n := len(t.names)
+	if len(args) < n {
+		n = len(args)
+	}
+	var fields []interface{}
+	if len(args) > n {
+		var errs []error
+		fields, errs = pairKeyvals(args[n:])
+		if len(fields)%2 == 1 {
+			fields = append(fields, nil)
+		}
+		appendErrors(e, errs)
+	}
+
+	vals := make([]interface{}, n)
+	var b strings.Builder
+	for i, name := range t.names {
+		b.WriteString(t.text[i])
+		if i >= n {
+			b.WriteString("{" + name + "}")
+			continue
+		}
+		vals[i] = resolve(args[i])
+		fmt.Fprint(&b, vals[i])
+	}
+	b.WriteString(t.text[len(t.names)])
+
+	e.Str(MessageFieldName, b.String())
+	e.Str(TemplateFieldName, tmpl)
+	keys := make([]string, 0, len(vals))
+	for i, v := range vals {
+		keys = append(keys, placeholderKey(keys, t.names[i]))
+		appendField(e, keys[i], v)
+	}
+	appendFields(e, fields)
+}
+
+// placeholderKey returns the field name of the placeholder name, given the
+// field names of the placeholders before it.
+func placeholderKey(keys []string, name string) string {
+	key := name
+	for n := 1; isEventField(key) || containsString(keys, key); n++ {
+		key = name + "_" + strconv.Itoa(n)
+	}
+	return key
+}
+
+// isEventField reports whether name is a field added to events by the
+// logger or by the templates themselves.
+func isEventField(name string) bool {
+	switch name {
+	case MessageFieldName, TemplateFieldName, LevelFieldName, TimestampFieldName, CallerFieldName:
+		return true
+	}
+	return false
+}
+
+func containsString(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package slog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		args []interface{}
+		want string
+	}{
+		{
+			name: "placeholders",
+			tmpl: "user {user} logged in from {ip}",
+			args: []interface{}{"alice", "10.0.0.1"},
+			want: `{"level":"info","msg":"user alice logged in from 10.0.0.1","tmpl":"user {user} logged in from {ip}","user":"alice","ip":"10.0.0.1"}`,
+		},
+		{
+			name: "typed values",
+			tmpl: "took {elapsed_ms}ms for {n} items",
+			args: []interface{}{12.5, 3},
+			want: `{"level":"info","msg":"took 12.5ms for 3 items","tmpl":"took {elapsed_ms}ms for {n} items","elapsed_ms":12.5,"n":3}`,
+		},
+		{
+			name: "missing argument",
+			tmpl: "hello {name}",
+			want: `{"level":"info","msg":"hello {name}","tmpl":"hello {name}"}`,
+		},
+		{
+			name: "extra arguments",
+			tmpl: "hello {name}",
+			args: []interface{}{"bob", "k", "v", errors.New("boom")},
+			want: `{"level":"info","error":"boom","msg":"hello bob","tmpl":"hello {name}","name":"bob","k":"v"}`,
+		},
+		{
+			name: "error only argument",
+			tmpl: "failed {err}",
+			args: []interface{}{errors.New("boom")},
+			want: `{"level":"info","msg":"failed boom","tmpl":"failed {err}","err":"boom"}`,
+		},
+		{
+			name: "error last argument",
+			tmpl: "user {user} failed: {err}",
+			args: []interface{}{"alice", errors.New("boom")},
+			want: `{"level":"info","msg":"user alice failed: boom","tmpl":"user {user} failed: {err}","user":"alice","err":"boom"}`,
+		},
+		{
+			name: "extra error arguments",
+			tmpl: "failed",
+			args: []interface{}{errors.New("a"), "k", errors.New("b")},
+			want: `{"level":"info","error":"a","msg":"failed","tmpl":"failed","k":"b"}`,
+		},
+		{
+			name: "dangling extra argument",
+			tmpl: "hello",
+			args: []interface{}{"k"},
+			want: `{"level":"info","msg":"hello","tmpl":"hello","k":null}`,
+		},
+		{
+			name: "escaped and literal braces",
+			tmpl: "{{name}} {not a name} {} {name}",
+			args: []interface{}{"x"},
+			want: `{"level":"info","msg":"{name} {not a name} {} x","tmpl":"{{name}} {not a name} {} {name}","name":"x"}`,
+		},
+		{
+			name: "repeated placeholder",
+			tmpl: "{a} {a} {a_1}",
+			args: []interface{}{1, 2, 3},
+			want: `{"level":"info","msg":"1 2 3","tmpl":"{a} {a} {a_1}","a":1,"a_1":2,"a_1_1":3}`,
+		},
+		{
+			name: "event field placeholders",
+			tmpl: "{level} {msg} {tmpl}",
+			args: []interface{}{"x", "y", "z"},
+			want: `{"level":"info","msg":"x y z","tmpl":"{level} {msg} {tmpl}","level_1":"x","msg_1":"y","tmpl_1":"z"}`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			l := &Helper{log: newZerolog(buf)}
+			l.Infot(tt.tmpl, tt.args...)
+			assert.JSONEq(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestTemplate_disabled(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := &Helper{log: newZerolog(buf)}
+	n := 0
+	l.Debugt("value {v}", countingStringer{&n})
+	assert.Equal(t, 0, n)
+	assert.Empty(t, buf.String())
+}
+
+func TestTemplate_cacheBound(t *testing.T) {
+	for i := 0; i < 2*maxTemplates; i++ {
+		parseTemplate(fmt.Sprintf("dynamic %d {v}", i))
+	}
+	n := 0
+	templates.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+	assert.LessOrEqual(t, n, maxTemplates)
+	assert.LessOrEqual(t, int(atomic.LoadInt32(&templateCount)), maxTemplates)
+
+	// templates beyond the bound are still rendered.
+	buf := new(bytes.Buffer)
+	(&Helper{log: newZerolog(buf)}).Infot("uncached {v}", 1)
+	assert.JSONEq(t, `{"level":"info","msg":"uncached 1","tmpl":"uncached {v}","v":1}`, buf.String())
+}
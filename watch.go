@@ -0,0 +1,102 @@
+package slog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// WatchInterval is how often Watch polls the config file for changes.
+var WatchInterval = time.Second
+
+// Watch loads the JSON encoded Config at path, applies it to the default
+// logger and keeps polling the file, applying the level, outputs, rotation
+// settings and format again whenever its content changes. The default
+// logger is looked up each time, a logger set by ReplaceGlobals or Init
+// after Watch is configured as well. Events logged while the new config is
+// applied are not lost, and log files whose settings did not change are
+// kept open.
+//
+// The returned func stops watching.
+func Watch(path string) (func(), error) {
+	return watch(defaultHelper, path, WatchInterval)
+}
+
+// defaultHelper returns the default logger, which must be a *Helper to be
+// configured.
+func defaultHelper() (*Helper, error) {
+	l := DefaultLogger()
+	h, ok := l.(*Helper)
+	if !ok {
+		return nil, fmt.Errorf("slog: watch: unsupported logger %T", l)
+	}
+	return h, nil
+}
+
+func watch(logger func() (*Helper, error), path string, interval time.Duration) (func(), error) {
+	h, err := logger()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyConfig(h, data); err != nil {
+		return nil, fmt.Errorf("slog: watch %s: %w", path, err)
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		applied := h
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			next, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			h, err := logger()
+			if err != nil {
+				// reported once per change of the file.
+				if !bytes.Equal(next, data) {
+					data = next
+					DefaultLogger().Error(err)
+				}
+				continue
+			}
+			if h == applied && bytes.Equal(next, data) {
+				continue
+			}
+			data, applied = next, h
+			if err := applyConfig(h, data); err != nil {
+				h.Errorf("slog: watch %s: %v", path, err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}, nil
+}
+
+func applyConfig(h *Helper, data []byte) error {
+	c := new(Config)
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, c); err != nil {
+		return err
+	}
+	return h.apply(c)
+}
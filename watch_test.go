@@ -0,0 +1,99 @@
+package slog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_watch(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "log.json")
+	file := filepath.Join(dir, "app.log")
+
+	write := func(s string) {
+		require.NoError(t, os.WriteFile(cfg, []byte(s), 0o644))
+	}
+	read := func() string {
+		b, _ := os.ReadFile(file)
+		return string(b)
+	}
+
+	write(`{"level":"warn","path":"` + file + `"}`)
+
+	h := New(nil).(*Helper)
+	stop, err := watch(helper(h), cfg, 10*time.Millisecond)
+	require.NoError(t, err)
+	defer stop()
+
+	h.Info("dropped")
+	h.Warn("kept")
+	assert.NotContains(t, read(), "dropped")
+	assert.Contains(t, read(), "kept")
+
+	currentFile := func() interface{} {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.out.sinks["file"]
+	}
+	first := currentFile()
+	write(`{"level":"debug","path":"` + file + `"}`)
+	reloaded := h.Debug
+	if !debugEnabled {
+		reloaded = h.Info
+	}
+	assert.Eventually(t, func() bool {
+		reloaded("reloaded")
+		return strings.Contains(read(), "reloaded")
+	}, time.Second, 20*time.Millisecond)
+	assert.Equal(t, first, currentFile(), "unchanged file must not be reopened")
+
+	write(`{"level":"debug","path":"` + file + `","max_size":10}`)
+	assert.Eventually(t, func() bool {
+		return currentFile() != first
+	}, time.Second, 20*time.Millisecond)
+}
+
+func Test_watch_invalid(t *testing.T) {
+	cfg := filepath.Join(t.TempDir(), "log.json")
+	require.NoError(t, os.WriteFile(cfg, []byte("{"), 0o644))
+
+	_, err := watch(helper(New(nil).(*Helper)), cfg, time.Second)
+	assert.Error(t, err)
+
+	_, err = watch(helper(New(nil).(*Helper)), cfg+".missing", time.Second)
+	assert.Error(t, err)
+
+	defer ReplaceGlobals(struct{ FullLogger }{New(nil)})()
+	_, err = Watch(cfg)
+	assert.Error(t, err)
+}
+
+func helper(h *Helper) func() (*Helper, error) {
+	return func() (*Helper, error) { return h, nil }
+}
+
+func TestWatch_replaceGlobals(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "log.json")
+	file := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(cfg, []byte(`{"level":"warn","path":"`+file+`"}`), 0o644))
+
+	defer ReplaceGlobals(New(nil))()
+	stop, err := watch(defaultHelper, cfg, 10*time.Millisecond)
+	require.NoError(t, err)
+	defer stop()
+
+	// the config is applied to the new default logger, the file unchanged.
+	ReplaceGlobals(New(nil))
+	assert.Eventually(t, func() bool {
+		Warn("replaced")
+		b, _ := os.ReadFile(file)
+		return strings.Contains(string(b), "replaced")
+	}, time.Second, 20*time.Millisecond)
+}
@@ -1,38 +1,96 @@
 package slog
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	zlog "github.com/rs/zerolog"
-	"github.com/rs/zerolog/pkgerrors"
 )
 
+// newZerolog returns a zerolog writing the events to w as JSON, see
+// jsonEvents.
 func newZerolog(w io.Writer) *zerolog {
+	return newZerologRaw(jsonEvents(w))
+}
+
+// newZerologRaw returns a zerolog writing the events to w as encoded by
+// zerolog, for the writers of an output which pick their own encoding.
+func newZerologRaw(w io.Writer) *zerolog {
 	initialize()
-	return &zerolog{
-		log:   zlog.New(w),
-		level: LevelInfo,
-		w:     w,
-	}
+	z := new(zerolog)
+	z.state.Store(&zerologState{log: zlog.New(w), w: w, level: LevelInfo})
+	return z
 }
 
 var _ KLogger = (*zerolog)(nil)
 var _ Control = (*zerolog)(nil)
 
-// zerolog implements a Logger interface using zerolog.
+// zerolog implements a Logger interface using zerolog. Log reads the state
+// without locking, the updates replace the state as a whole.
 type zerolog struct {
-	log   zlog.Logger
-	level Level
-	mu    sync.Mutex
-	w     io.Writer
+	state atomic.Value // *zerologState
+	mu    sync.Mutex   // serializes the updates of state.
+}
+
+// zerologState is the configuration of a zerolog, never modified once
+// stored.
+type zerologState struct {
+	log        zlog.Logger
+	w          io.Writer
+	level      Level
+	caller     bool
+	callerSkip int
+	callerFmt  *Caller
+	// callerLog is log with the caller hook, set by store when the caller
+	// is enabled.
+	callerLog zlog.Logger
+	// lazy holds the key/value pairs of the context fields with Lazy values.
+	lazy []interface{}
+}
+
+func (z *zerolog) load() *zerologState {
+	return z.state.Load().(*zerologState)
+}
+
+// update stores a copy of the state modified by f.
+func (z *zerolog) update(f func(s *zerologState)) *zerolog {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	s := *z.load()
+	f(&s)
+	z.store(&s)
+	return z
+}
+
+// store publishes the state s, with its caller hook.
+func (z *zerolog) store(s *zerologState) {
+	if s.caller {
+		s.callerLog = s.log.Hook(callerHook{s})
+	}
+	z.state.Store(s)
+}
+
+// callerHook adds the caller to the events of a state, after the fields of
+// the other hooks such as the timestamp. The caller is looked up when the
+// event is sent, without allocating per event.
+type callerHook struct {
+	s *zerologState
+}
+
+func (h callerHook) Run(e *zlog.Event, _ zlog.Level, _ string) {
+	if f, ok := callerFrame(h.s.callerSkip); ok {
+		callerFields(e, f, h.s.callerFmt)
+	}
 }
 
 func (z *zerolog) Log(lv Level, kvs ...interface{}) error {
-	if z.level > lv {
+	if !z.Enabled(lv) {
 		return nil
 	}
 
@@ -40,112 +98,374 @@ func (z *zerolog) Log(lv Level, kvs ...interface{}) error {
 		return nil
 	}
 
-	var e *zlog.Event
+	e := z.event(lv)
+	if isPlain(kvs) {
+		// nothing to convert, the common case.
+		if len(kvs)%2 == 1 {
+			appendMessage(e, kvs[0])
+			kvs = kvs[1:]
+		}
+		e.Fields(kvs)
+		e.Send()
+		return nil
+	}
+
+	msg, fields, errs := splitKeyvals(kvs)
+	appendErrors(e, errs)
+	if len(msg) == 1 {
+		appendMessage(e, resolve(msg[0]))
+	}
+	if len(fields) > 0 {
+		appendFields(e, fields)
+	}
+	e.Send()
+
+	return nil
+}
+
+// appendMessage adds the message m to e.
+func appendMessage(e *zlog.Event, m interface{}) {
+	if s, ok := m.(string); ok {
+		e.Str(MessageFieldName, s)
+	} else {
+		e.Fields([]interface{}{MessageFieldName, m})
+	}
+}
+
+// appendErrors adds errs to e: a single error with its kratos error fields
+// and chain, several errors as a list.
+func appendErrors(e *zlog.Event, errs []error) {
+	switch len(errs) {
+	case 0:
+	case 1:
+		e.Err(errs[0])
+		errorFields(e, errs[0])
+		if ErrorChainMarshaler != nil {
+			if chain := ErrorChainMarshaler(errs[0]); chain != nil {
+				e.Interface(ErrorChainFieldName, chain)
+			}
+		}
+	default:
+		e.Errs(ErrorsFieldName, errs)
+	}
+}
+
+// logf logs the message formatted from format and v, formatting only when lv
+// is enabled.
+func (z *zerolog) logf(lv Level, format string, v []interface{}) {
+	if !z.Enabled(lv) {
+		return
+	}
+	e := z.event(lv)
+	// the message comes before the caller and is kept when empty, as with
+	// Log.
+	buf := formatBufs.Get().(*bytes.Buffer)
+	buf.Reset()
+	fmt.Fprintf(buf, format, resolveAll(v)...)
+	if binaryLog {
+		// Bytes would be a CBOR byte string.
+		e.Str(MessageFieldName, buf.String())
+	} else {
+		e.Bytes(MessageFieldName, buf.Bytes())
+	}
+	if buf.Cap() <= maxFormatBuf {
+		formatBufs.Put(buf)
+	}
+	e.Send()
+}
+
+// formatBufs holds the buffers formatting the messages of logf, the larger
+// than maxFormatBuf are not kept.
+var formatBufs = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+const maxFormatBuf = 64 << 10
 
+// logt logs the message rendered from the template tmpl and v, rendering
+// only when lv is enabled.
+func (z *zerolog) logt(lv Level, tmpl string, v []interface{}) {
+	if !z.Enabled(lv) {
+		return
+	}
+	e := z.event(lv)
+	parseTemplate(tmpl).write(e, tmpl, v)
+	e.Send()
+}
+
+// event starts an event of level lv, with the caller when enabled and the
+// Lazy context fields computed.
+func (z *zerolog) event(lv Level) *zlog.Event {
+	s := z.load()
+	log := s.log
+	if s.caller {
+		log = s.callerLog
+	}
+
+	var e *zlog.Event
 	switch lv {
 	case LevelDebug:
-		e = z.log.Debug()
-	case LevelInfo:
-		e = z.log.Info()
+		e = log.Debug()
 	case LevelWarn:
-		e = z.log.Warn()
+		e = log.Warn()
 	case LevelError:
-		e = z.log.Error()
+		e = log.Error()
 	case LevelFatal:
-		e = z.log.Fatal()
+		e = log.Fatal()
 	default:
-		e = z.log.Info()
+		e = log.Info()
 	}
+	if len(s.lazy) > 0 && e != nil {
+		appendFields(e, s.lazy)
+	}
+	return e
+}
 
-	for i, v := range kvs {
-		if err, ok := v.(error); ok {
-			e.Err(err)
-			kvs = append(kvs[:i], kvs[i+1:]...)
+// splitKeyvals splits kvs into an optional message, key/value fields and
+// errors, without modifying kvs. Errors at the start of kvs or where a key
+// is expected are logged as errors, an error following a key is the value
+// of that key. The first element is the message when only errors follow it,
+// when the elements do not form key/value pairs otherwise, or when reading
+// it as the message keeps more errors before the last element paired with
+// their keys. A key left without value gets a null value.
+func splitKeyvals(kvs []interface{}) (msg, fields []interface{}, errs []error) {
+	first := 0
+	for first < len(kvs) && isError(kvs[first]) {
+		errs = append(errs, kvs[first].(error))
+		first++
+	}
+	if first == len(kvs) {
+		return nil, nil, errs
+	}
+
+	if onlyErrors(kvs[first+1:]) {
+		for _, v := range kvs[first+1:] {
+			errs = append(errs, v.(error))
 		}
+		return kvs[first : first+1], nil, errs
 	}
 
-	if len(kvs) == 0 {
-		e.Send()
-		return nil
+	// a last error is the value of a key left without one, it does not
+	// decide where the message is.
+	last := len(kvs)
+	if isError(kvs[last-1]) {
+		last--
+	}
+	fields, inner := pairKeyvals(kvs[first:last])
+	f, in := pairKeyvals(kvs[first+1 : last])
+	dangling := last == len(kvs)
+	if dangling && len(fields)%2 == 1 || (!dangling || len(f)%2 == 0) && len(in) < len(inner) {
+		msg, fields, inner = kvs[first:first+1], f, in
+	}
+	errs = append(errs, inner...)
+
+	switch {
+	case last < len(kvs) && len(fields)%2 == 1:
+		fields = append(fields, kvs[last])
+	case last < len(kvs):
+		errs = append(errs, kvs[last].(error))
+	case len(fields)%2 == 1:
+		fields = append(fields, nil)
 	}
+	return msg, fields, errs
+}
 
-	if len(kvs)%2 == 0 {
-		e.Fields(kvs)
-	} else {
-		if s, ok := kvs[0].(string); ok {
-			e.Str(MessageFieldName, s)
-		} else {
-			e.Fields([]interface{}{MessageFieldName, kvs[0]})
+// onlyErrors reports whether all values of kvs are errors.
+func onlyErrors(kvs []interface{}) bool {
+	for _, v := range kvs {
+		if !isError(v) {
+			return false
 		}
+	}
+	return true
+}
 
-		if len(kvs) > 1 {
-			e.Fields(kvs[1:])
+// isPlain reports whether kvs has neither errors, Lazy values nor values
+// converted by fieldValue, so that it can be logged as is.
+func isPlain(kvs []interface{}) bool {
+	for _, v := range kvs {
+		switch v.(type) {
+		case nil, string, bool, int, int64, int32, uint, uint64, uint32, float64, float32:
+			// checked first, the interface cases are much slower.
+			continue
+		case error, Lazy:
+			return false
+		}
+		if hasMarshaler(v) {
+			return false
 		}
 	}
+	return true
+}
 
-	e.Send()
+// pairKeyvals copies the key/value pairs of kvs, skipping the errors found
+// where a key is expected.
+func pairKeyvals(kvs []interface{}) (fields []interface{}, errs []error) {
+	fields = make([]interface{}, 0, len(kvs))
+	for _, v := range kvs {
+		if err, ok := v.(error); ok && len(fields)%2 == 0 {
+			errs = append(errs, err)
+			continue
+		}
+		fields = append(fields, v)
+	}
+	return fields, errs
+}
 
-	return nil
+func isError(v interface{}) bool {
+	_, ok := v.(error)
+	return ok
+}
+
+// resolve computes the value of a Lazy.
+func resolve(v interface{}) interface{} {
+	if l, ok := v.(Lazy); ok {
+		return l()
+	}
+	return v
+}
+
+// resolveAll returns v with its Lazy values computed, v is copied only when
+// it holds some.
+func resolveAll(v []interface{}) []interface{} {
+	for i, x := range v {
+		if _, ok := x.(Lazy); ok {
+			r := make([]interface{}, len(v))
+			copy(r, v[:i])
+			for j := i; j < len(v); j++ {
+				r[j] = resolve(v[j])
+			}
+			return r
+		}
+	}
+	return v
+}
+
+// Enabled reports whether events of level lv are logged.
+func (z *zerolog) Enabled(lv Level) bool {
+	if !debugEnabled && lv <= LevelDebug {
+		return false
+	}
+	return lv >= z.load().level
+}
+
+// levelOf converts a zerolog level to the matching log level.
+func levelOf(l zlog.Level) Level {
+	switch l {
+	case zlog.TraceLevel, zlog.DebugLevel:
+		return LevelDebug
+	case zlog.WarnLevel:
+		return LevelWarn
+	case zlog.ErrorLevel:
+		return LevelError
+	case zlog.FatalLevel, zlog.PanicLevel:
+		return LevelFatal
+	}
+	return LevelInfo
 }
 
 // SetLevel sets the current global log level.
 func (z *zerolog) SetLevel(l Level) Control {
-	z.mu.Lock()
-	defer z.mu.Unlock()
-	z.level = l
-	return z
+	return z.update(func(s *zerologState) {
+		s.level = l
+	})
 }
 
+// SetOutput sets the output of the events, w receives them as JSON even
+// when zerolog encodes them as CBOR.
 func (z *zerolog) SetOutput(w io.Writer) Control {
-	z.mu.Lock()
-	defer z.mu.Unlock()
-	z.log = z.log.Output(w)
-	return z
+	return z.update(func(s *zerologState) {
+		s.setOutput(jsonEvents(w))
+	})
+}
+
+func (s *zerologState) setOutput(w io.Writer) {
+	s.log = s.log.Output(w)
+	s.w = w
 }
 
+// Clone returns a copy of z with its level, output, context fields, hooks
+// and caller options, the changes of either do not affect the other.
 func (z *zerolog) Clone() *zerolog {
-	z2 := newZerolog(z.w)
-	z2.mu.Lock()
-	defer z2.mu.Unlock()
-	z2.level = z.level
-	z2.log = z.log.With().Logger()
-	z2.w = z.w
+	z2 := new(zerolog)
+	s := *z.load()
+	// Output copies the context and the hooks of the logger.
+	s.log = s.log.Output(s.w)
+	z2.store(&s)
 	return z2
 }
 
 func (z *zerolog) WithTimestamp() *zerolog {
-	z.mu.Lock()
-	defer z.mu.Unlock()
-	z.log = z.log.With().Timestamp().Logger()
-	return z
+	return z.update(func(s *zerologState) {
+		s.log = s.log.With().Timestamp().Logger()
+	})
 }
 
 func (z *zerolog) WithCaller() *zerolog {
-	z.mu.Lock()
-	defer z.mu.Unlock()
-	z.log = z.log.With().Caller().Logger()
-	return z
+	return z.update(func(s *zerologState) {
+		s.caller = true
+	})
 }
 
 func (z *zerolog) WithCallerWithSkipFrameCount(skipFrameCount int) *zerolog {
-	z.mu.Lock()
-	defer z.mu.Unlock()
-	z.log = z.log.With().CallerWithSkipFrameCount(CallerSkipFrameCount + skipFrameCount).Logger()
-	return z
+	return z.update(func(s *zerologState) {
+		s.caller = true
+		s.callerSkip = skipFrameCount
+	})
+}
+
+// setCaller enables the caller in the format of c, or disables it if c is
+// nil.
+func (z *zerolog) setCaller(c *Caller) *zerolog {
+	return z.update(func(s *zerologState) {
+		s.setCaller(c)
+	})
+}
+
+func (s *zerologState) setCaller(c *Caller) {
+	s.caller = c != nil
+	s.callerFmt = c
+}
+
+// AddCallerSkip skips skip more frames above the first caller that is not a
+// helper when reporting the caller.
+func (z *zerolog) AddCallerSkip(skip int) *zerolog {
+	return z.update(func(s *zerologState) {
+		s.callerSkip += skip
+	})
 }
 
 func (z *zerolog) WithStack() *zerolog {
-	z.mu.Lock()
-	defer z.mu.Unlock()
-	z.log = z.log.With().Stack().Logger()
-	return z
+	return z.update(func(s *zerologState) {
+		s.log = s.log.With().Stack().Logger()
+	})
 }
 
+// WithFields adds fields to each event, with their values converted by
+// fieldValue. Lazy values are computed for each event, after the other
+// fields.
 func (z *zerolog) WithFields(fields ...interface{}) *zerolog {
-	z.mu.Lock()
-	defer z.mu.Unlock()
-	z.log = z.log.With().Fields(fields).Logger()
-	return z
+	return z.update(func(s *zerologState) {
+		if isPlain(fields) {
+			s.log = s.log.With().Fields(fields).Logger()
+			return
+		}
+		ctx := s.log.With()
+		var lazy []interface{}
+		for i := 0; i+1 < len(fields); i += 2 {
+			if _, ok := fields[i+1].(Lazy); ok {
+				lazy = append(lazy, fields[i], fields[i+1])
+				continue
+			}
+			ctx = ctx.Fields([]interface{}{fields[i], fieldValue(fields[i+1])})
+		}
+		if len(fields)%2 == 1 {
+			ctx = ctx.Fields(fields[len(fields)-1:])
+		}
+		s.log = ctx.Logger()
+		if len(lazy) > 0 {
+			// copied, the state may be shared with clones.
+			s.lazy = append(s.lazy[:len(s.lazy):len(s.lazy)], lazy...)
+		}
+	})
 }
 
 var (
@@ -182,13 +502,28 @@ var (
 	// MessageFieldName is the field name used for the message field.
 	MessageFieldName = "msg"
 
+	// TemplateFieldName is the field name used for the template of the
+	// messages logged with a template, see TemplateLogger.
+	TemplateFieldName = "tmpl"
+
 	// ErrorFieldName is the field name used for error fields.
 	ErrorFieldName = "error"
 
 	// CallerFieldName is the field name used for caller field.
 	CallerFieldName = "caller"
 
+	// CallerFuncFieldName is the field name used for the calling function,
+	// see Caller.Func.
+	CallerFuncFieldName = "func"
+
+	// GoroutineFieldName is the field name used for the id of the calling
+	// goroutine, see Caller.Goroutine.
+	GoroutineFieldName = "goroutine"
+
 	// CallerSkipFrameCount is the number of stack frames to skip to find the caller.
+	//
+	// Deprecated: the frames of this package and of the helpers marked with
+	// MarkHelper are skipped, use AddCallerSkip to skip more frames.
 	CallerSkipFrameCount = 2
 
 	// CallerMarshalFunc allows customization of global caller marshaling
@@ -208,7 +543,30 @@ var (
 	ErrorStackFieldName = "stack"
 
 	// ErrorStackMarshaler extract the stack from err if any.
-	ErrorStackMarshaler = pkgerrors.MarshalStack
+	ErrorStackMarshaler = MarshalStack
+
+	// ErrorsFieldName is the field name used when several errors are logged
+	// at once.
+	ErrorsFieldName = "errors"
+
+	// ErrorCodeFieldName is the field name used for the code of kratos errors.
+	ErrorCodeFieldName = "error_code"
+
+	// ErrorReasonFieldName is the field name used for the reason of kratos
+	// errors.
+	ErrorReasonFieldName = "error_reason"
+
+	// ErrorMetadataFieldName is the field name used for the metadata of kratos
+	// errors.
+	ErrorMetadataFieldName = "error_metadata"
+
+	// ErrorChainFieldName is the field name used for the chain of wrapped
+	// errors.
+	ErrorChainFieldName = "error_chain"
+
+	// ErrorChainMarshaler extract the chain of wrapped errors from err if any,
+	// nil disables the field.
+	ErrorChainMarshaler = MarshalErrorChain
 
 	// ErrorMarshalFunc allows customization of global error marshaling
 	ErrorMarshalFunc = func(err error) interface{} {
@@ -2,10 +2,16 @@ package slog
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_zerolog_Log(t *testing.T) {
@@ -86,6 +92,118 @@ func Test_zerolog_Log(t *testing.T) {
 	}
 }
 
+func Test_zerolog_Log_errors(t *testing.T) {
+	err1, err2 := errors.New("e1"), errors.New("e2")
+	tests := []struct {
+		name string
+		kvs  []interface{}
+		want string
+	}{
+		{
+			name: "single error",
+			kvs:  []interface{}{err1},
+			want: `{"level":"info","error":"e1"}`,
+		},
+		{
+			name: "message and error",
+			kvs:  []interface{}{"failed", err1},
+			want: `{"level":"info","error":"e1","msg":"failed"}`,
+		},
+		{
+			name: "adjacent errors",
+			kvs:  []interface{}{"failed", err1, err2},
+			want: `{"level":"info","errors":["e1","e2"],"msg":"failed"}`,
+		},
+		{
+			name: "fields then error",
+			kvs:  []interface{}{"k", "v", err1},
+			want: `{"level":"info","error":"e1","k":"v"}`,
+		},
+		{
+			name: "error as the last value",
+			kvs:  []interface{}{"k", "v", "cause", err1},
+			want: `{"level":"info","k":"v","cause":"e1"}`,
+		},
+		{
+			name: "errors as values",
+			kvs:  []interface{}{"cause", err1, "other", err2},
+			want: `{"level":"info","cause":"e1","other":"e2"}`,
+		},
+		{
+			name: "message and error as the last value",
+			kvs:  []interface{}{"failed", "k", err1, err2},
+			want: `{"level":"info","error":"e2","msg":"failed","k":"e1"}`,
+		},
+		{
+			name: "message, fields and error as the last value",
+			kvs:  []interface{}{"failed", "k", "v", "cause", err1, err2},
+			want: `{"level":"info","error":"e2","msg":"failed","k":"v","cause":"e1"}`,
+		},
+		{
+			name: "leading error",
+			kvs:  []interface{}{err1, "failed", "k", "v"},
+			want: `{"level":"info","error":"e1","msg":"failed","k":"v"}`,
+		},
+		{
+			name: "error value",
+			kvs:  []interface{}{"k", "v", "cause", err1, "n", 1},
+			want: `{"level":"info","k":"v","cause":"e1","n":1}`,
+		},
+		{
+			name: "message and error value",
+			kvs:  []interface{}{"failed", "cause", err1, "n", 1},
+			want: `{"level":"info","msg":"failed","cause":"e1","n":1}`,
+		},
+		{
+			name: "error value and standalone error",
+			kvs:  []interface{}{"k", "v", "cause", err1, err2, "n", 1},
+			want: `{"level":"info","error":"e2","k":"v","cause":"e1","n":1}`,
+		},
+		{
+			name: "dangling key",
+			kvs:  []interface{}{"failed", err1, "x"},
+			want: `{"level":"info","error":"e1","msg":"failed","x":null}`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			kvs := append([]interface{}(nil), tt.kvs...)
+			w := &bytes.Buffer{}
+			z := newZerolog(w)
+			assert.NoError(t, z.Log(LevelInfo, kvs...))
+			assert.JSONEq(t, tt.want, w.String())
+			assert.Equal(t, tt.kvs, kvs, "kvs must not be modified")
+		})
+	}
+}
+
+func Test_zerolog_logf(t *testing.T) {
+	logged := func(log func(z *zerolog)) string {
+		buf := new(bytes.Buffer)
+		z := newZerolog(buf).setCaller(&Caller{})
+		log(z)
+		// the caller line differs.
+		return regexp.MustCompile(`:\d+"`).ReplaceAllString(buf.String(), `"`)
+	}
+
+	tests := []struct {
+		format string
+		args   []interface{}
+	}{
+		{format: ""},
+		{format: "hello"},
+		{format: "hello %s", args: []interface{}{"world"}},
+		{format: "%d%% \"done\"", args: []interface{}{100}},
+	}
+	for _, tt := range tests {
+		want := logged(func(z *zerolog) { z.Log(LevelInfo, fmt.Sprintf(tt.format, tt.args...)) })
+		got := logged(func(z *zerolog) { z.logf(LevelInfo, tt.format, tt.args) })
+		assert.Equal(t, want, got, "same as Log for %q", tt.format)
+	}
+	assert.Regexp(t, `^\{"level":"info","msg":"","caller":`, logged(func(z *zerolog) { z.logf(LevelInfo, "", nil) }))
+}
+
 func Test_zerolog_SetLevel(t *testing.T) {
 	tests := []struct {
 		name string
@@ -109,3 +227,238 @@ func Test_zerolog_SetOutput(t *testing.T) {
 	z.Log(LevelInfo, "test")
 	assert.Contains(t, w.String(), "test")
 }
+
+func Test_zerolog_Lazy(t *testing.T) {
+	calls := 0
+	expensive := Lazy(func() interface{} {
+		calls++
+		return "computed"
+	})
+
+	w := &bytes.Buffer{}
+	z := newZerolog(w)
+	z.Log(LevelDebug, "skipped", "value", expensive)
+	assert.Equal(t, 0, calls)
+	assert.Empty(t, w.String())
+
+	z.Log(LevelInfo, "logged", "value", expensive)
+	assert.Equal(t, 1, calls)
+	assert.JSONEq(t, `{"level":"info","msg":"logged","value":"computed"}`, w.String())
+
+	w.Reset()
+	args := []interface{}{"n", expensive}
+	z.logf(LevelDebug, "skipped %s", args[1:])
+	assert.Equal(t, 1, calls)
+	z.logf(LevelInfo, "%s value %s", args)
+	assert.Equal(t, 2, calls)
+	assert.JSONEq(t, `{"level":"info","msg":"n value computed"}`, w.String())
+	assert.IsType(t, expensive, args[1], "args must not be modified")
+}
+
+func Test_zerolog_WithFields_lazy(t *testing.T) {
+	calls := 0
+	counter := Lazy(func() interface{} {
+		calls++
+		return calls
+	})
+
+	w := &bytes.Buffer{}
+	z := newZerolog(w).WithFields("n", counter, "user", user{name: "alice"}, "svc", "api")
+	clone := z.Clone().WithFields("other", Lazy(func() interface{} { return "clone" }))
+
+	z.Log(LevelDebug, "skipped")
+	assert.Equal(t, 0, calls, "computed only for the logged events")
+
+	z.Log(LevelInfo, "first")
+	z.newEvent(LevelInfo).Msg("second")
+	assert.Equal(t, `{"level":"info","user":{"name":"alice","roles":[]},"svc":"api","n":1,"msg":"first"}
+{"level":"info","user":{"name":"alice","roles":[]},"svc":"api","n":2,"msg":"second"}
+`, w.String())
+
+	w.Reset()
+	clone.Log(LevelInfo, "clone")
+	assert.JSONEq(t, `{"level":"info","user":{"name":"alice","roles":[]},"svc":"api","n":3,"other":"clone","msg":"clone"}`, w.String())
+
+	w.Reset()
+	z.Log(LevelInfo, "unchanged by the clone")
+	assert.NotContains(t, w.String(), "other")
+}
+
+func Test_zerolog_Enabled(t *testing.T) {
+	z := newZerolog(nil)
+	z.SetLevel(LevelWarn)
+	assert.False(t, z.Enabled(LevelInfo))
+	assert.True(t, z.Enabled(LevelWarn))
+	assert.True(t, z.Enabled(LevelError))
+}
+
+func Test_zerolog_concurrency(t *testing.T) {
+	const (
+		loggers = 8
+		events  = 500
+	)
+	tests := []struct {
+		name   string
+		mutate func(z *zerolog, w1, w2 *syncBuffer, i int)
+	}{
+		{
+			name: "SetLevel",
+			mutate: func(z *zerolog, _, _ *syncBuffer, i int) {
+				z.SetLevel([]Level{LevelDebug, LevelInfo, LevelError}[i%3])
+			},
+		},
+		{
+			name: "SetOutput",
+			mutate: func(z *zerolog, w1, w2 *syncBuffer, i int) {
+				if i%2 == 0 {
+					z.SetOutput(w1)
+				} else {
+					z.SetOutput(w2)
+				}
+			},
+		},
+		{
+			name: "With",
+			mutate: func(z *zerolog, _, _ *syncBuffer, i int) {
+				switch {
+				case i < 64:
+					z.WithFields(fmt.Sprint("f", i), i)
+				case i%3 == 0:
+					z.Clone().Log(LevelInfo, "clone")
+				case i%3 == 1:
+					z.WithCaller()
+				default:
+					z.AddCallerSkip(0)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w1, w2 := new(syncBuffer), new(syncBuffer)
+			z := newZerolog(w1)
+
+			var wg sync.WaitGroup
+			done := make(chan struct{})
+			wg.Add(loggers)
+			for i := 0; i < loggers; i++ {
+				go func() {
+					defer wg.Done()
+					for j := 0; j < events; j++ {
+						z.Log(LevelInfo, "msg", "j", j)
+						z.Enabled(LevelDebug)
+					}
+				}()
+			}
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				for i := 0; ; i++ {
+					select {
+					case <-done:
+						return
+					default:
+						tt.mutate(z, w1, w2, i)
+					}
+				}
+			}()
+			wg.Wait()
+			close(done)
+			// the mutator may still be logging, later tests reset the
+			// zerolog globals.
+			<-stopped
+
+			// every event written is complete.
+			w1.events(t)
+			w2.events(t)
+		})
+	}
+}
+
+func Test_zerolog_Clone(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(z *zerolog, w *bytes.Buffer)
+		verify func(t *testing.T, z, clone *zerolog, w *bytes.Buffer)
+	}{
+		{
+			name: "after SetOutput",
+			setup: func(z *zerolog, w *bytes.Buffer) {
+				z.SetOutput(w)
+			},
+			verify: func(t *testing.T, z, clone *zerolog, w *bytes.Buffer) {
+				clone.Log(LevelInfo, "clone")
+				assert.JSONEq(t, `{"level":"info","msg":"clone"}`, w.String())
+			},
+		},
+		{
+			name: "after WithFields",
+			setup: func(z *zerolog, w *bytes.Buffer) {
+				z.SetOutput(w)
+				z.WithFields("app", "demo")
+			},
+			verify: func(t *testing.T, z, clone *zerolog, w *bytes.Buffer) {
+				clone.WithFields("child", true)
+				clone.Log(LevelInfo, "clone")
+				assert.JSONEq(t, `{"level":"info","app":"demo","child":true,"msg":"clone"}`, w.String())
+
+				w.Reset()
+				z.Log(LevelInfo, "parent")
+				assert.JSONEq(t, `{"level":"info","app":"demo","msg":"parent"}`, w.String())
+			},
+		},
+		{
+			name: "level",
+			setup: func(z *zerolog, w *bytes.Buffer) {
+				z.SetOutput(w)
+				z.SetLevel(LevelWarn)
+			},
+			verify: func(t *testing.T, z, clone *zerolog, w *bytes.Buffer) {
+				assert.False(t, clone.Enabled(LevelInfo))
+				clone.SetLevel(LevelDebug)
+				assert.Equal(t, debugEnabled, clone.Enabled(LevelDebug))
+				assert.True(t, clone.Enabled(LevelInfo))
+				assert.False(t, z.Enabled(LevelInfo))
+			},
+		},
+		{
+			name: "hooks and options",
+			setup: func(z *zerolog, w *bytes.Buffer) {
+				z.SetOutput(w)
+				z.WithTimestamp().setCaller(&Caller{Func: true}).AddCallerSkip(0)
+			},
+			verify: func(t *testing.T, z, clone *zerolog, w *bytes.Buffer) {
+				clone.Log(LevelInfo, "clone")
+				var e map[string]interface{}
+				require.NoError(t, json.Unmarshal(w.Bytes(), &e))
+				assert.Contains(t, e, TimestampFieldName)
+				assert.Regexp(t, `^zerolog_test\.go:\d+$`, e[CallerFieldName])
+				assert.Contains(t, e, CallerFuncFieldName)
+			},
+		},
+		{
+			name: "SetOutput of the clone",
+			setup: func(z *zerolog, w *bytes.Buffer) {
+				z.SetOutput(w)
+			},
+			verify: func(t *testing.T, z, clone *zerolog, w *bytes.Buffer) {
+				other := &bytes.Buffer{}
+				clone.SetOutput(other)
+				z.Log(LevelInfo, "parent")
+				clone.Log(LevelInfo, "clone")
+				assert.JSONEq(t, `{"level":"info","msg":"parent"}`, w.String())
+				assert.JSONEq(t, `{"level":"info","msg":"clone"}`, other.String())
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			z := newZerolog(nil)
+			tt.setup(z, w)
+			tt.verify(t, z, z.Clone(), w)
+		})
+	}
+}